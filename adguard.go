@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+type AdGuardHeader struct {
+	Title        string
+	Description  string
+	Version      string
+	LastModified time.Time
+}
+
+type AdGuardWriter struct {
+	w *bufio.Writer
+}
+
+func NewAdGuardWriter(w io.Writer) *AdGuardWriter {
+	return &AdGuardWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *AdGuardWriter) WriteHeader(h AdGuardHeader) error {
+	meta := []struct{ key, value string }{
+		{"Title", h.Title},
+		{"Description", h.Description},
+		{"Version", h.Version},
+	}
+	for _, m := range meta {
+		if strings.ContainsAny(m.value, "\r\n") {
+			return &AdGuardHeaderError{Key: m.key, Value: m.value}
+		}
+	}
+
+	w.writeMeta("Title", h.Title)
+	w.writeMeta("Description", h.Description)
+	w.writeMeta("Version", h.Version)
+	if !h.LastModified.IsZero() {
+		w.writeMeta("Last modified", h.LastModified.UTC().Format(time.RFC3339))
+	}
+	_, err := w.w.WriteString("!\n")
+	return err
+}
+
+func (w *AdGuardWriter) writeMeta(key, value string) {
+	if value == "" {
+		return
+	}
+	w.w.WriteString("! ")
+	w.w.WriteString(key)
+	w.w.WriteString(": ")
+	w.w.WriteString(value)
+	w.w.WriteByte('\n')
+}
+
+func (w *AdGuardWriter) Write(f filter.Filter) error {
+	_, err := w.w.WriteString(FormatAdGuardRule(f) + "\n")
+	return err
+}
+
+func (w *AdGuardWriter) Flush() error {
+	return w.w.Flush()
+}
+
+func FormatAdGuardRule(f filter.Filter) string {
+	var b strings.Builder
+	if f.Exception {
+		b.WriteString("@@")
+	}
+
+	switch f.Kind {
+	case filter.KindRegex:
+		writeAdGuardRegex(&b, f.Pattern)
+	case filter.KindGlob:
+		writeAdGuardRegex(&b, filter.GlobToRegex(f.Pattern))
+	default:
+		b.WriteString("||")
+		b.WriteString(f.Domain)
+		b.WriteByte('^')
+	}
+
+	if f.Exception {
+		b.WriteString("$important")
+	}
+	return b.String()
+}
+
+func writeAdGuardRegex(b *strings.Builder, expr string) {
+	b.WriteByte('/')
+	escaped := false
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		if c == '/' && !escaped {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+		escaped = c == '\\' && !escaped
+	}
+	b.WriteByte('/')
+}
+
+type AdGuardHeaderError struct {
+	Key   string
+	Value string
+}
+
+func (e *AdGuardHeaderError) Error() string {
+	return "adguard header " + e.Key + ": line break in value " + strconv.Quote(e.Value)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+func TestAdGuardWriter(t *testing.T) {
+	var sb strings.Builder
+	w := NewAdGuardWriter(&sb)
+
+	err := w.WriteHeader(AdGuardHeader{
+		Title:        "admasq",
+		Version:      "20240102.1",
+		LastModified: time.Date(2024, 1, 2, 12, 34, 56, 0, time.FixedZone("JST", 9*60*60)),
+	})
+	if err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+
+	fs := []filter.Filter{
+		{Domain: "ads.example.com"},
+		{Exception: true, Domain: "good.example.com"},
+	}
+	for _, f := range fs {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	const want = "" +
+		"! Title: admasq\n" +
+		"! Version: 20240102.1\n" +
+		"! Last modified: 2024-01-02T03:34:56Z\n" +
+		"!\n" +
+		"||ads.example.com^\n" +
+		"@@||good.example.com^$important\n"
+	got := sb.String()
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestFormatAdGuardRule(t *testing.T) {
+	tt := []struct {
+		in   filter.Filter
+		want string
+	}{
+		{
+			in:   filter.Filter{Domain: "example.com"},
+			want: "||example.com^",
+		},
+		{
+			in:   filter.Filter{Exception: true, Domain: "example.com"},
+			want: "@@||example.com^$important",
+		},
+		{
+			in:   filter.Filter{Kind: filter.KindRegex, Pattern: `^ad[0-9]*\.`},
+			want: `/^ad[0-9]*\./`,
+		},
+		{
+			in:   filter.Filter{Kind: filter.KindRegex, Pattern: `a/b\/c`},
+			want: `/a\/b\/c/`,
+		},
+		{
+			in:   filter.Filter{Exception: true, Kind: filter.KindGlob, Pattern: "*.example.com"},
+			want: `@@/^.*\.example\.com$/$important`,
+		},
+	}
+
+	for _, tc := range tt {
+		got := FormatAdGuardRule(tc.in)
+		if got != tc.want {
+			t.Errorf("%v: expected %q, got %q", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestAdGuardWriterHeaderError(t *testing.T) {
+	tt := []AdGuardHeader{
+		{Title: "admasq\n||example.com^"},
+		{Description: "line\r@@||ads.example.com^"},
+		{Version: "1\n"},
+	}
+
+	for _, tc := range tt {
+		var sb strings.Builder
+		w := NewAdGuardWriter(&sb)
+		err := w.WriteHeader(tc)
+		var headerErr *AdGuardHeaderError
+		if !errors.As(err, &headerErr) {
+			t.Errorf("%+v: expected *AdGuardHeaderError, got %#v", tc, err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+		if got := sb.String(); got != "" {
+			t.Errorf("%+v: expected %q, got %q", tc, "", got)
+		}
+	}
+}
+
+// HelpAdGuardSplitOptions splits a network rule into its pattern and
+// modifiers the way AdGuard's rule parser does: a rule that starts and ends
+// with "/" is a bare regex, otherwise the last unescaped "$" delimits the
+// modifiers.
+func HelpAdGuardSplitOptions(rule string) (pattern, options string) {
+	rule = strings.TrimPrefix(rule, "@@")
+	if len(rule) > 1 && strings.HasPrefix(rule, "/") && strings.HasSuffix(rule, "/") {
+		return rule, ""
+	}
+	for i := len(rule) - 2; i >= 0; i-- {
+		if rule[i] == '$' && (i == 0 || rule[i-1] != '\\') {
+			return rule[:i], rule[i+1:]
+		}
+	}
+	return rule, ""
+}
+
+func TestFormatAdGuardRuleOptions(t *testing.T) {
+	tt := []struct {
+		in          filter.Filter
+		wantPattern string
+		wantOptions string
+	}{
+		{
+			in:          filter.Filter{Exception: true, Domain: "example.com"},
+			wantPattern: "||example.com^",
+			wantOptions: "important",
+		},
+		{
+			in:          filter.Filter{Kind: filter.KindRegex, Pattern: `^ad[0-9]*\.example$`},
+			wantPattern: `/^ad[0-9]*\.example$/`,
+			wantOptions: "",
+		},
+		{
+			in:          filter.Filter{Exception: true, Kind: filter.KindRegex, Pattern: `^ad[0-9]*\.example$`},
+			wantPattern: `/^ad[0-9]*\.example$/`,
+			wantOptions: "important",
+		},
+		{
+			in:          filter.Filter{Exception: true, Kind: filter.KindGlob, Pattern: "*.example.com"},
+			wantPattern: `/^.*\.example\.com$/`,
+			wantOptions: "important",
+		},
+	}
+
+	for _, tc := range tt {
+		gotPattern, gotOptions := HelpAdGuardSplitOptions(FormatAdGuardRule(tc.in))
+		if gotPattern != tc.wantPattern {
+			t.Errorf("%v: pattern: expected %q, got %q", tc.in, tc.wantPattern, gotPattern)
+		}
+		if gotOptions != tc.wantOptions {
+			t.Errorf("%v: options: expected %q, got %q", tc.in, tc.wantOptions, gotOptions)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+type BuildState struct {
+	Sources map[string]int `json:"sources"`
+}
+
+func NewBuildState() *BuildState {
+	return &BuildState{Sources: map[string]int{}}
+}
+
+func ReadBuildState(path string) (*BuildState, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewBuildState(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewBuildState()
+	err = json.Unmarshal(b, s)
+	if err != nil {
+		return nil, err
+	}
+	if s.Sources == nil {
+		s.Sources = map[string]int{}
+	}
+	return s, nil
+}
+
+func WriteBuildState(path string, s *BuildState) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(b)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func (s *BuildState) Update(name string, count int) {
+	s.Sources[name] = count
+}
+
+type AnomalyPolicy struct {
+	MinRatio     float64
+	MaxRatio     float64
+	KeepPrevious bool
+}
+
+func (p AnomalyPolicy) Check(s *BuildState, name string, count int) error {
+	prev, ok := s.Sources[name]
+	if !ok || prev <= 0 {
+		return nil
+	}
+
+	ratio := float64(count) / float64(prev)
+	if (p.MinRatio > 0 && ratio < p.MinRatio) || (p.MaxRatio > 0 && ratio > p.MaxRatio) {
+		return &SourceCountError{Name: name, Prev: prev, Count: count}
+	}
+	return nil
+}
+
+// Accept checks the filters loaded for a source against the recorded count.
+// An accepted source has its count recorded in s and cur is returned. An
+// anomalous source keeps its old count; if the policy keeps previous data and
+// prev is not nil, prev is returned along with the error so that the caller
+// can still report it, otherwise nil is.
+func (p AnomalyPolicy) Accept(s *BuildState, name string, cur, prev []filter.Filter) ([]filter.Filter, error) {
+	err := p.Check(s, name, len(cur))
+	if err != nil {
+		if p.KeepPrevious && prev != nil {
+			return prev, err
+		}
+		return nil, err
+	}
+	s.Update(name, len(cur))
+	return cur, nil
+}
+
+type SourceCountError struct {
+	Name  string
+	Prev  int
+	Count int
+}
+
+func (e *SourceCountError) Error() string {
+	b := []byte("source ")
+	b = strconv.AppendQuote(b, e.Name)
+	b = append(b, ": filter count changed from "...)
+	b = strconv.AppendInt(b, int64(e.Prev), 10)
+	b = append(b, " to "...)
+	b = strconv.AppendInt(b, int64(e.Count), 10)
+	return string(b)
+}
+
+const sniffLen = 512
+
+func CheckSourceContent(name string, r io.Reader) (io.Reader, error) {
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	if content := sniffMarkup(head); content != "" {
+		return nil, &SourceContentError{Name: name, Content: content}
+	}
+	return br, nil
+}
+
+func sniffMarkup(head []byte) string {
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	head = bytes.TrimLeft(head, " \t\r\n")
+	if len(head) <= 0 {
+		return ""
+	}
+
+	switch head[0] {
+	case '<':
+		lower := bytes.ToLower(head)
+		for _, prefix := range []string{"<!doctype html", "<html", "<head", "<body"} {
+			if bytes.HasPrefix(lower, []byte(prefix)) {
+				return "HTML"
+			}
+		}
+		if bytes.HasPrefix(lower, []byte("<?xml")) {
+			return "XML"
+		}
+		if bytes.HasPrefix(lower, []byte("<!--")) {
+			return "markup"
+		}
+	case '{':
+		return "JSON"
+	case '[':
+		rest := bytes.TrimLeft(head[1:], " \t\r\n")
+		if len(rest) > 0 && bytes.IndexByte([]byte("{[]\"-0123456789tfn"), rest[0]) >= 0 {
+			return "JSON"
+		}
+	}
+	return ""
+}
+
+type SourceContentError struct {
+	Name    string
+	Content string
+}
+
+func (e *SourceContentError) Error() string {
+	return "source " + strconv.Quote(e.Name) + ": content looks like " + e.Content + ", not a domain list"
+}
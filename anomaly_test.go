@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+func TestBuildStateReadWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	s, err := ReadBuildState(path)
+	if err != nil {
+		t.Fatalf("read missing: %v", err)
+	}
+	if len(s.Sources) != 0 {
+		t.Errorf("read missing: expected empty state, got %v", s.Sources)
+	}
+
+	s.Sources["hosts.txt"] = 120000
+	s.Sources["simple.txt"] = 42
+	err = WriteBuildState(path, s)
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	got, err := ReadBuildState(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !reflect.DeepEqual(got, s) {
+		t.Errorf("expected %v, got %v", s, got)
+	}
+}
+
+func TestAnomalyPolicyCheck(t *testing.T) {
+	s := NewBuildState()
+	s.Sources["hosts.txt"] = 1000
+	s.Sources["empty.txt"] = 0
+	p := AnomalyPolicy{MinRatio: 0.5, MaxRatio: 3}
+
+	tt := []struct {
+		name    string
+		count   int
+		wantErr bool
+	}{
+		{"hosts.txt", 1000, false},
+		{"hosts.txt", 500, false},
+		{"hosts.txt", 499, true},
+		{"hosts.txt", 0, true},
+		{"hosts.txt", 3000, false},
+		{"hosts.txt", 3001, true},
+		{"empty.txt", 100, false},
+		{"new.txt", 0, false},
+	}
+
+	for _, tc := range tt {
+		err := p.Check(s, tc.name, tc.count)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s %d: expected error %t, got %v", tc.name, tc.count, tc.wantErr, err)
+		}
+		var countErr *SourceCountError
+		if err != nil && (!errors.As(err, &countErr) || countErr.Prev != s.Sources[tc.name] || countErr.Count != tc.count) {
+			t.Errorf("%s %d: unexpected error %#v", tc.name, tc.count, err)
+		}
+	}
+
+	if err := (AnomalyPolicy{}).Check(s, "hosts.txt", 0); err != nil {
+		t.Errorf("zero policy: expected no error, got %v", err)
+	}
+}
+
+func TestAnomalyPolicyAccept(t *testing.T) {
+	prev := []filter.Filter{{Domain: "a.example"}, {Domain: "b.example"}, {Domain: "c.example"}, {Domain: "d.example"}}
+	cur := []filter.Filter{{Domain: "a.example"}}
+
+	tt := []struct {
+		name      string
+		policy    AnomalyPolicy
+		cur       []filter.Filter
+		prev      []filter.Filter
+		want      []filter.Filter
+		wantErr   bool
+		wantCount int
+	}{
+		{name: "Accept", policy: AnomalyPolicy{MinRatio: 0.5}, cur: prev[:3], prev: prev, want: prev[:3], wantCount: 3},
+		{name: "Refuse", policy: AnomalyPolicy{MinRatio: 0.5}, cur: cur, prev: prev, want: nil, wantErr: true, wantCount: 4},
+		{name: "KeepPrevious", policy: AnomalyPolicy{MinRatio: 0.5, KeepPrevious: true}, cur: cur, prev: prev, want: prev, wantErr: true, wantCount: 4},
+		{name: "KeepPreviousNone", policy: AnomalyPolicy{MinRatio: 0.5, KeepPrevious: true}, cur: cur, prev: nil, want: nil, wantErr: true, wantCount: 4},
+	}
+
+	for _, tc := range tt {
+		s := NewBuildState()
+		s.Update("hosts.txt", 4)
+
+		got, err := tc.policy.Accept(s, "hosts.txt", tc.cur, tc.prev)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+		var countErr *SourceCountError
+		if tc.wantErr != errors.As(err, &countErr) {
+			t.Errorf("%s: expected *SourceCountError %t, got %#v", tc.name, tc.wantErr, err)
+		}
+		if s.Sources["hosts.txt"] != tc.wantCount {
+			t.Errorf("%s: state: expected %d, got %d", tc.name, tc.wantCount, s.Sources["hosts.txt"])
+		}
+	}
+}
+
+func TestSourceCountErrorError(t *testing.T) {
+	err := &SourceCountError{Name: "hosts.txt", Prev: 1000, Count: 3}
+	want := `source "hosts.txt": filter count changed from 1000 to 3`
+	if got := err.Error(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCheckSourceContent(t *testing.T) {
+	tt := []struct {
+		in          string
+		wantContent string
+	}{
+		{"", ""},
+		{"0.0.0.0 example.com\n", ""},
+		{"# comment\nexample.com\n", ""},
+		{"[Adblock Plus 2.0]\n||example.com^\n", ""},
+		{"\xef\xbb\xbf\n  <!DOCTYPE html>\n<html></html>", "HTML"},
+		{"<html><body>502 Bad Gateway</body></html>", "HTML"},
+		{"<?xml version=\"1.0\"?>\n<error/>", "XML"},
+		{"<!-- generated -->\n<html></html>", "markup"},
+		{`{"error": "not found"}`, "JSON"},
+		{`[ "example.com" ]`, "JSON"},
+		{strings.Repeat(" ", 1000) + "example.com", ""},
+	}
+
+	for _, tc := range tt {
+		r, err := CheckSourceContent("src", strings.NewReader(tc.in))
+		if tc.wantContent == "" {
+			if err != nil {
+				t.Errorf("%q: expected no error, got %v", tc.in, err)
+				continue
+			}
+			b, _ := io.ReadAll(r)
+			if string(b) != tc.in {
+				t.Errorf("%q: content not preserved, got %q", tc.in, b)
+			}
+			continue
+		}
+
+		var contentErr *SourceContentError
+		if !errors.As(err, &contentErr) || contentErr.Content != tc.wantContent || contentErr.Name != "src" {
+			t.Errorf("%q: expected %s content error, got %#v", tc.in, tc.wantContent, err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+type BlockyWriter struct {
+	deny  *bufio.Writer
+	allow *bufio.Writer
+}
+
+func NewBlockyWriter(deny, allow io.Writer) *BlockyWriter {
+	return &BlockyWriter{
+		deny:  bufio.NewWriter(deny),
+		allow: bufio.NewWriter(allow),
+	}
+}
+
+func (w *BlockyWriter) Write(f filter.Filter) error {
+	bw := w.deny
+	if f.Exception {
+		bw = w.allow
+	}
+
+	_, err := bw.WriteString(FormatBlockyEntry(f) + "\n")
+	return err
+}
+
+func (w *BlockyWriter) Flush() error {
+	if err := w.deny.Flush(); err != nil {
+		return err
+	}
+	return w.allow.Flush()
+}
+
+func FormatBlockyEntry(f filter.Filter) string {
+	switch f.Kind {
+	case filter.KindRegex:
+		return "/" + f.Pattern + "/"
+	case filter.KindGlob:
+		return "/" + filter.GlobToRegex(f.Pattern) + "/"
+	default:
+		return f.Domain
+	}
+}
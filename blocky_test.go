@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+func TestBlockyWriter(t *testing.T) {
+	var deny, allow strings.Builder
+	w := NewBlockyWriter(&deny, &allow)
+
+	fs := []filter.Filter{
+		{Domain: "ads.example.com"},
+		{Exception: true, Domain: "good.example.com"},
+		{Kind: filter.KindRegex, Pattern: `^ad[0-9]*\.`},
+		{Exception: true, Kind: filter.KindGlob, Pattern: "*.allowed.example"},
+	}
+	for _, f := range fs {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	const wantDeny = "ads.example.com\n/^ad[0-9]*\\./\n"
+	if got := deny.String(); got != wantDeny {
+		t.Errorf("deny: expected %q, got %q", wantDeny, got)
+	}
+
+	const wantAllow = "good.example.com\n/^.*\\.allowed\\.example$/\n"
+	if got := allow.String(); got != wantAllow {
+		t.Errorf("allow: expected %q, got %q", wantAllow, got)
+	}
+}
+
+func TestFormatBlockyEntry(t *testing.T) {
+	tt := []struct {
+		in   filter.Filter
+		want string
+	}{
+		{in: filter.Filter{Domain: "example.com"}, want: "example.com"},
+		{in: filter.Filter{Kind: filter.KindRegex, Pattern: "^ad"}, want: "/^ad/"},
+		{in: filter.Filter{Kind: filter.KindGlob, Pattern: "*.example.com"}, want: `/^.*\.example\.com$/`},
+		{in: filter.Filter{Kind: filter.KindGlob, Pattern: "ad?.example.com"}, want: `/^ad.\.example\.com$/`},
+	}
+
+	for _, tc := range tt {
+		got := FormatBlockyEntry(tc.in)
+		if got != tc.want {
+			t.Errorf("%v: expected %q, got %q", tc.in, tc.want, got)
+		}
+	}
+}
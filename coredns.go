@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+// CoreDNSHostsWriter writes a file for the CoreDNS hosts plugin. The hosts
+// plugin answers exact names only, so a blocked domain does not cover its
+// subdomains as it does in the dnsmasq, AdGuard and template outputs.
+type CoreDNSHostsWriter struct {
+	w      *bufio.Writer
+	blocks []string
+	allow  map[string]bool
+}
+
+func NewCoreDNSHostsWriter(w io.Writer) *CoreDNSHostsWriter {
+	return &CoreDNSHostsWriter{w: bufio.NewWriter(w), allow: map[string]bool{}}
+}
+
+func (w *CoreDNSHostsWriter) Write(f filter.Filter) error {
+	if f.Kind != filter.KindDomain {
+		return ErrPatternUnsupported
+	}
+
+	if f.Exception {
+		w.allow[f.Domain] = true
+	} else {
+		w.blocks = append(w.blocks, f.Domain)
+	}
+	return nil
+}
+
+func (w *CoreDNSHostsWriter) Flush() error {
+	for _, domain := range subtractAllowed(w.blocks, w.allow) {
+		w.w.WriteString("0.0.0.0 " + domain + "\n")
+	}
+	w.blocks = nil
+	w.allow = map[string]bool{}
+	return w.w.Flush()
+}
+
+type CoreDNSTemplateWriter struct {
+	w        io.Writer
+	maxZones int
+	zones    []string
+	allow    map[string]bool
+}
+
+func NewCoreDNSTemplateWriter(w io.Writer) *CoreDNSTemplateWriter {
+	return &CoreDNSTemplateWriter{w: w, maxZones: 8, allow: map[string]bool{}}
+}
+
+func (w *CoreDNSTemplateWriter) SetMaxZones(n int) {
+	w.maxZones = n
+}
+
+func (w *CoreDNSTemplateWriter) Write(f filter.Filter) error {
+	if f.Kind != filter.KindDomain {
+		return ErrPatternUnsupported
+	}
+
+	if f.Exception {
+		w.allow[f.Domain] = true
+	} else {
+		w.zones = append(w.zones, f.Domain)
+	}
+	return nil
+}
+
+func (w *CoreDNSTemplateWriter) Flush() error {
+	zones := subtractAllowed(w.zones, w.allow)
+	blocked := make(map[string]bool, len(zones))
+	for _, zone := range zones {
+		blocked[zone] = true
+	}
+	for domain := range w.allow {
+		for parent := parentDomain(domain); parent != ""; parent = parentDomain(parent) {
+			if blocked[parent] {
+				return &CoreDNSExceptionError{Domain: domain, Zone: parent}
+			}
+		}
+	}
+	w.zones = nil
+	w.allow = map[string]bool{}
+
+	n := w.maxZones
+	if n <= 0 {
+		n = len(zones)
+	}
+
+	var b []byte
+	for len(zones) > 0 {
+		chunk := zones[:min(n, len(zones))]
+		zones = zones[len(chunk):]
+
+		b = append(b, "template IN ANY"...)
+		for _, zone := range chunk {
+			b = append(b, ' ')
+			b = append(b, zone...)
+		}
+		b = append(b, " {\n    rcode NXDOMAIN\n}\n"...)
+	}
+	if len(b) <= 0 {
+		return nil
+	}
+
+	_, err := w.w.Write(b)
+	return err
+}
+
+type CoreDNSExceptionError struct {
+	Domain string
+	Zone   string
+}
+
+func (e *CoreDNSExceptionError) Error() string {
+	return "exception " + e.Domain + " is inside blocked zone " + e.Zone + ", which a template cannot express"
+}
+
+func subtractAllowed(domains []string, allow map[string]bool) []string {
+	var ret []string
+	for _, domain := range domains {
+		if !isAllowed(domain, allow) {
+			ret = append(ret, domain)
+		}
+	}
+	return ret
+}
+
+func isAllowed(domain string, allow map[string]bool) bool {
+	for ; domain != ""; domain = parentDomain(domain) {
+		if allow[domain] {
+			return true
+		}
+	}
+	return false
+}
+
+func parentDomain(domain string) string {
+	_, parent, _ := strings.Cut(domain, ".")
+	return parent
+}
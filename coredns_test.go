@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+func TestCoreDNSHostsWriter(t *testing.T) {
+	var sb strings.Builder
+	w := NewCoreDNSHostsWriter(&sb)
+
+	for _, f := range []filter.Filter{{Domain: "ads.example.com"}, {Domain: "tracker.example"}} {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	const want = "0.0.0.0 ads.example.com\n0.0.0.0 tracker.example\n"
+	got := sb.String()
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCoreDNSHostsWriterUnsupported(t *testing.T) {
+	w := NewCoreDNSHostsWriter(&strings.Builder{})
+
+	err := w.Write(filter.Filter{Kind: filter.KindRegex, Pattern: "^ad"})
+	if err != ErrPatternUnsupported {
+		t.Errorf("regex: expected %#v, got %#v", ErrPatternUnsupported, err)
+	}
+}
+
+func TestCoreDNSHostsWriterException(t *testing.T) {
+	var sb strings.Builder
+	w := NewCoreDNSHostsWriter(&sb)
+
+	fs := []filter.Filter{
+		{Domain: "example.com"},
+		{Domain: "good.example.com"},
+		{Domain: "cdn.good.example.com"},
+		{Domain: "ads.example.com"},
+		{Exception: true, Domain: "good.example.com"},
+	}
+	for _, f := range fs {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	const want = "0.0.0.0 example.com\n0.0.0.0 ads.example.com\n"
+	got := sb.String()
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCoreDNSTemplateWriter(t *testing.T) {
+	var sb strings.Builder
+	w := NewCoreDNSTemplateWriter(&sb)
+
+	for _, f := range []filter.Filter{{Domain: "ads.example.com"}, {Domain: "tracker.example"}} {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	const want = "" +
+		"template IN ANY ads.example.com tracker.example {\n" +
+		"    rcode NXDOMAIN\n" +
+		"}\n"
+	got := sb.String()
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCoreDNSTemplateWriterEmpty(t *testing.T) {
+	var sb strings.Builder
+	w := NewCoreDNSTemplateWriter(&sb)
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if sb.Len() != 0 {
+		t.Errorf("expected empty output, got %q", sb.String())
+	}
+}
+
+func TestCoreDNSTemplateWriterWrap(t *testing.T) {
+	var sb strings.Builder
+	w := NewCoreDNSTemplateWriter(&sb)
+	w.SetMaxZones(2)
+
+	for _, f := range []filter.Filter{{Domain: "a.example"}, {Domain: "b.example"}, {Domain: "c.example"}} {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	const want = "" +
+		"template IN ANY a.example b.example {\n" +
+		"    rcode NXDOMAIN\n" +
+		"}\n" +
+		"template IN ANY c.example {\n" +
+		"    rcode NXDOMAIN\n" +
+		"}\n"
+	got := sb.String()
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCoreDNSTemplateWriterException(t *testing.T) {
+	var sb strings.Builder
+	w := NewCoreDNSTemplateWriter(&sb)
+
+	fs := []filter.Filter{
+		{Domain: "ads.example.com"},
+		{Domain: "good.example.com"},
+		{Domain: "cdn.good.example.com"},
+		{Exception: true, Domain: "good.example.com"},
+	}
+	for _, f := range fs {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	const want = "" +
+		"template IN ANY ads.example.com {\n" +
+		"    rcode NXDOMAIN\n" +
+		"}\n"
+	got := sb.String()
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCoreDNSTemplateWriterNestedException(t *testing.T) {
+	var sb strings.Builder
+	w := NewCoreDNSTemplateWriter(&sb)
+
+	for _, f := range []filter.Filter{{Domain: "example.com"}, {Exception: true, Domain: "good.example.com"}} {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	err := w.Flush()
+	var excErr *CoreDNSExceptionError
+	if !errors.As(err, &excErr) || excErr.Domain != "good.example.com" || excErr.Zone != "example.com" {
+		t.Errorf("expected *CoreDNSExceptionError, got %#v", err)
+	}
+	if sb.Len() != 0 {
+		t.Errorf("expected empty output, got %q", sb.String())
+	}
+}
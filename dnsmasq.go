@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+type DnsmasqWriter struct {
+	w        *bufio.Writer
+	annotate bool
+	display  filter.DisplayMode
+}
+
+func NewDnsmasqWriter(w io.Writer) *DnsmasqWriter {
+	return &DnsmasqWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *DnsmasqWriter) SetAnnotate(annotate bool) {
+	w.annotate = annotate
+}
+
+func (w *DnsmasqWriter) SetDisplayMode(mode filter.DisplayMode) {
+	w.display = mode
+}
+
+func (w *DnsmasqWriter) Write(f filter.Filter) error {
+	if f.Kind != filter.KindDomain {
+		return ErrPatternUnsupported
+	}
+
+	if w.annotate {
+		comment := escapeComment(f.Source())
+		if d := w.display.Format(f.Domain); d != f.Domain {
+			if comment != "" {
+				comment += " "
+			}
+			comment += d
+		}
+		if comment != "" {
+			w.w.WriteString("# " + comment + "\n")
+		}
+	}
+
+	var err error
+	if f.Exception {
+		_, err = w.w.WriteString("server=/" + f.Domain + "/#\n")
+	} else {
+		_, err = w.w.WriteString("address=/" + f.Domain + "/\n")
+	}
+	return err
+}
+
+func (w *DnsmasqWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// escapeComment quotes the control characters in s, so that a source name
+// cannot end the comment line and start a directive.
+func escapeComment(s string) string {
+	if !strings.ContainsFunc(s, unicode.IsControl) {
+		return s
+	}
+
+	var b []byte
+	for _, r := range s {
+		if !unicode.IsControl(r) {
+			b = utf8.AppendRune(b, r)
+			continue
+		}
+		q := strconv.QuoteRuneToASCII(r)
+		b = append(b, q[1:len(q)-1]...)
+	}
+	return string(b)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+func TestDnsmasqWriter(t *testing.T) {
+	var sb strings.Builder
+	w := NewDnsmasqWriter(&sb)
+
+	fs := []filter.Filter{
+		{Domain: "ads.example.com", Name: "hosts.txt", Line: 12},
+		{Exception: true, Domain: "good.example.com", Name: "allow.txt", Line: 3},
+	}
+	for _, f := range fs {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	const want = "address=/ads.example.com/\nserver=/good.example.com/#\n"
+	got := sb.String()
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestDnsmasqWriterAnnotate(t *testing.T) {
+	var sb strings.Builder
+	w := NewDnsmasqWriter(&sb)
+	w.SetAnnotate(true)
+
+	fs := []filter.Filter{
+		{Domain: "ads.example.com", Name: "hosts.txt", Line: 12},
+		{Domain: "tracker.example"},
+	}
+	for _, f := range fs {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	const want = "" +
+		"# hosts.txt:12\n" +
+		"address=/ads.example.com/\n" +
+		"address=/tracker.example/\n"
+	got := sb.String()
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestDnsmasqWriterAnnotateControl(t *testing.T) {
+	var sb strings.Builder
+	w := NewDnsmasqWriter(&sb)
+	w.SetAnnotate(true)
+
+	f := filter.Filter{Domain: "ads.example.com", Name: "list\naddress=/x/1.2.3.4\r", Line: 1}
+	if err := w.Write(f); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	const want = "" +
+		"# list\\naddress=/x/1.2.3.4\\r:1\n" +
+		"address=/ads.example.com/\n"
+	got := sb.String()
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	for _, line := range strings.Split(got, "\n") {
+		if strings.HasPrefix(line, "address=/x/") {
+			t.Errorf("unexpected directive %q", line)
+		}
+	}
+}
+
+func TestDnsmasqWriterDisplayMode(t *testing.T) {
+	var sb strings.Builder
+	w := NewDnsmasqWriter(&sb)
+	w.SetAnnotate(true)
+	w.SetDisplayMode(filter.DisplayBoth)
+
+	fs := []filter.Filter{
+		{Domain: "xn--t8jx73hngb.com", Name: "simple.txt", Line: 3},
+		{Domain: "example.com", Name: "simple.txt", Line: 4},
+	}
+	for _, f := range fs {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	const want = "" +
+		"# simple.txt:3 お名前.com (xn--t8jx73hngb.com)\n" +
+		"address=/xn--t8jx73hngb.com/\n" +
+		"# simple.txt:4\n" +
+		"address=/example.com/\n"
+	got := sb.String()
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestDnsmasqWriterPattern(t *testing.T) {
+	w := NewDnsmasqWriter(&strings.Builder{})
+	err := w.Write(filter.Filter{Kind: filter.KindRegex, Pattern: "^ad"})
+	if err != ErrPatternUnsupported {
+		t.Errorf("expected %#v, got %#v", ErrPatternUnsupported, err)
+	}
+}
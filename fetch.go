@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+var ErrTransportUnsupported = errors.New("address restrictions require an *http.Transport")
+
+type Fetcher struct {
+	client       *http.Client
+	maxSize      int64
+	timeout      time.Duration
+	idleTimeout  time.Duration
+	schemes      []string
+	denyPrivate  bool
+	proxy        bool
+	maxRedirects int
+
+	transport    *http.Transport
+	transportErr error
+	denied       func(netip.Addr) bool
+}
+
+func NewFetcher() *Fetcher {
+	return &Fetcher{
+		client:       http.DefaultClient,
+		schemes:      []string{"http", "https"},
+		maxRedirects: 10,
+		denied:       isPrivateAddr,
+	}
+}
+
+func (f *Fetcher) SetClient(c *http.Client) {
+	f.client = c
+	f.resetTransport()
+}
+
+func (f *Fetcher) SetMaxSize(n int64) {
+	f.maxSize = n
+}
+
+func (f *Fetcher) SetTimeout(d time.Duration) {
+	f.timeout = d
+}
+
+func (f *Fetcher) SetIdleTimeout(d time.Duration) {
+	f.idleTimeout = d
+}
+
+func (f *Fetcher) SetSchemes(schemes ...string) {
+	f.schemes = schemes
+}
+
+// SetDenyPrivate refuses connections to loopback, private and other
+// non-public addresses. The check needs the client's transport to be an
+// *http.Transport; Fetch returns ErrTransportUnsupported otherwise.
+func (f *Fetcher) SetDenyPrivate(deny bool) {
+	f.denyPrivate = deny
+	f.resetTransport()
+}
+
+// SetProxy controls whether the transport's proxy is used while private
+// addresses are denied. By default it is not: a proxy connects on our behalf,
+// so only the proxy's own address could be checked. Allowing it trusts the
+// proxy to apply its own restrictions.
+func (f *Fetcher) SetProxy(allow bool) {
+	f.proxy = allow
+	f.resetTransport()
+}
+
+func (f *Fetcher) SetMaxRedirects(n int) {
+	f.maxRedirects = n
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	err = f.checkScheme(u)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := f.newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if f.timeout > 0 {
+		timer := time.AfterFunc(f.timeout, func() {
+			cancel(&FetchTimeoutError{URL: rawURL})
+		})
+		defer timer.Stop()
+	}
+	var idle *time.Timer
+	if f.idleTimeout > 0 {
+		idle = time.AfterFunc(f.idleTimeout, func() {
+			cancel(&FetchTimeoutError{URL: rawURL, Idle: true})
+		})
+		defer idle.Stop()
+	}
+
+	b, err := f.fetch(ctx, c, rawURL, idle)
+	if cause := context.Cause(ctx); err != nil && cause != nil && cause != context.Canceled {
+		var timeoutErr *FetchTimeoutError
+		if errors.As(cause, &timeoutErr) {
+			return nil, timeoutErr
+		}
+	}
+	return b, err
+}
+
+func (f *Fetcher) fetch(ctx context.Context, c *http.Client, rawURL string, idle *time.Timer) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &FetchStatusError{URL: rawURL, StatusCode: resp.StatusCode}
+	}
+	if f.maxSize > 0 && resp.ContentLength > f.maxSize {
+		return nil, &FetchSizeError{URL: rawURL, Limit: f.maxSize}
+	}
+
+	var r io.Reader = resp.Body
+	if idle != nil {
+		r = &idleReader{r: r, t: idle, d: f.idleTimeout}
+	}
+	if f.maxSize > 0 {
+		r = io.LimitReader(r, f.maxSize+1)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if f.maxSize > 0 && int64(len(b)) > f.maxSize {
+		return nil, &FetchSizeError{URL: rawURL, Limit: f.maxSize}
+	}
+	return b, nil
+}
+
+func (f *Fetcher) newClient() (*http.Client, error) {
+	c := *f.client
+	c.CheckRedirect = f.checkRedirect
+
+	if f.denyPrivate {
+		if f.transportErr != nil {
+			return nil, f.transportErr
+		}
+		c.Transport = f.transport
+	}
+	return &c, nil
+}
+
+// guardedTransport clones the client's transport with a dialer that checks
+// every destination address.
+func (f *Fetcher) guardedTransport() (*http.Transport, error) {
+	base := f.client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t, ok := base.(*http.Transport)
+	if !ok {
+		return nil, ErrTransportUnsupported
+	}
+
+	t = t.Clone()
+	if !f.proxy {
+		t.Proxy = nil
+	}
+	d := &net.Dialer{Control: f.control}
+	t.DialContext = d.DialContext
+	return t, nil
+}
+
+// resetTransport builds the guarded transport once per configuration, so
+// that Fetch reuses its connections, and closes the previous one.
+func (f *Fetcher) resetTransport() {
+	f.CloseIdleConnections()
+	f.transport = nil
+	f.transportErr = nil
+	if f.denyPrivate {
+		f.transport, f.transportErr = f.guardedTransport()
+	}
+}
+
+// CloseIdleConnections closes the idle connections of the transport built
+// for SetDenyPrivate. Connections of the client's own transport are left to
+// the client.
+func (f *Fetcher) CloseIdleConnections() {
+	if f.transport != nil {
+		f.transport.CloseIdleConnections()
+	}
+}
+
+func (f *Fetcher) checkScheme(u *url.URL) error {
+	if f.schemes != nil && !slices.Contains(f.schemes, u.Scheme) {
+		return &FetchSchemeError{URL: u.String(), Scheme: u.Scheme}
+	}
+	return nil
+}
+
+func (f *Fetcher) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > f.maxRedirects {
+		return &FetchRedirectError{URL: via[0].URL.String(), Limit: f.maxRedirects}
+	}
+	return f.checkScheme(req.URL)
+}
+
+func (f *Fetcher) control(network, address string, c syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if f.denied(ap.Addr().Unmap()) {
+		return &FetchAddressError{Addr: ap.Addr().Unmap()}
+	}
+	return nil
+}
+
+var (
+	thisNetwork        = netip.MustParsePrefix("0.0.0.0/8")
+	sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+	benchmarkNetwork   = netip.MustParsePrefix("198.18.0.0/15")
+	nat64Prefix        = netip.MustParsePrefix("64:ff9b::/96")
+	sixToFourPrefix    = netip.MustParsePrefix("2002::/16")
+)
+
+func isPrivateAddr(ip netip.Addr) bool {
+	if nat64Prefix.Contains(ip) {
+		b := ip.As16()
+		return isPrivateAddr(netip.AddrFrom4([4]byte(b[12:])))
+	}
+	if sixToFourPrefix.Contains(ip) {
+		b := ip.As16()
+		return isPrivateAddr(netip.AddrFrom4([4]byte(b[2:6])))
+	}
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		thisNetwork.Contains(ip) ||
+		sharedAddressSpace.Contains(ip) ||
+		benchmarkNetwork.Contains(ip)
+}
+
+type idleReader struct {
+	r io.Reader
+	t *time.Timer
+	d time.Duration
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.t.Reset(r.d)
+	}
+	return n, err
+}
+
+func (f *Fetcher) FetchVerified(ctx context.Context, rawURL string, in Integrity) ([]byte, error) {
+	b, err := f.Fetch(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.SHA256URL != "" {
+		sum, err := f.Fetch(ctx, in.SHA256URL)
+		if err != nil {
+			return nil, err
+		}
+		want, err := ParseSHA256File(sum, rawURL)
+		if err != nil {
+			return nil, &IntegrityError{URL: rawURL, Err: err}
+		}
+		err = VerifySHA256(b, want)
+		if err != nil {
+			return nil, &IntegrityError{URL: rawURL, Err: err}
+		}
+	}
+
+	if len(in.PublicKeys) > 0 {
+		sigURL := in.SignatureURL
+		if sigURL == "" {
+			sigURL = rawURL + ".minisig"
+		}
+		sig, err := f.Fetch(ctx, sigURL)
+		if err != nil {
+			return nil, err
+		}
+		in.Signature = sig
+	}
+
+	err = in.Verify(b)
+	if err != nil {
+		return nil, &IntegrityError{URL: rawURL, Err: err}
+	}
+	return b, nil
+}
+
+type FetchStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *FetchStatusError) Error() string {
+	return e.URL + ": unexpected status " + strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode)
+}
+
+type FetchSizeError struct {
+	URL   string
+	Limit int64
+}
+
+func (e *FetchSizeError) Error() string {
+	return e.URL + ": response larger than " + strconv.FormatInt(e.Limit, 10) + " bytes"
+}
+
+type FetchTimeoutError struct {
+	URL  string
+	Idle bool
+}
+
+func (e *FetchTimeoutError) Error() string {
+	if e.Idle {
+		return e.URL + ": idle read timeout"
+	}
+	return e.URL + ": timeout"
+}
+
+func (e *FetchTimeoutError) Timeout() bool {
+	return true
+}
+
+type FetchSchemeError struct {
+	URL    string
+	Scheme string
+}
+
+func (e *FetchSchemeError) Error() string {
+	return e.URL + ": scheme " + strconv.Quote(e.Scheme) + " not allowed"
+}
+
+type FetchAddressError struct {
+	Addr netip.Addr
+}
+
+func (e *FetchAddressError) Error() string {
+	return "destination address " + e.Addr.String() + " not allowed"
+}
+
+type FetchRedirectError struct {
+	URL   string
+	Limit int
+}
+
+func (e *FetchRedirectError) Error() string {
+	return e.URL + ": stopped after " + strconv.Itoa(e.Limit) + " redirects"
+}
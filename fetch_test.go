@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func HelpFileServer(t *testing.T, files map[string][]byte) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestFetcherFetch(t *testing.T) {
+	ts := HelpFileServer(t, map[string][]byte{"/hosts.txt": []byte("0.0.0.0 example.com\n")})
+	f := NewFetcher()
+
+	got, err := f.Fetch(context.Background(), ts.URL+"/hosts.txt")
+	if err != nil || string(got) != "0.0.0.0 example.com\n" {
+		t.Errorf("expected content, got (%q, %v)", got, err)
+	}
+
+	_, err = f.Fetch(context.Background(), ts.URL+"/missing.txt")
+	var statusErr *FetchStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("missing: expected *FetchStatusError, got %#v", err)
+	}
+}
+
+func TestFetcherFetchVerified(t *testing.T) {
+	k := NewHelpMinisignKey(t, 1)
+	pub, _ := ParseMinisignPublicKey(k.PublicKey())
+	body := []byte("0.0.0.0 example.com\n")
+
+	ts := HelpFileServer(t, map[string][]byte{
+		"/hosts.txt":         body,
+		"/hosts.txt.sha256":  []byte(HelpSHA256(body) + "  hosts.txt\n"),
+		"/hosts.txt.minisig": k.Sign("ED", body, "timestamp:1700000000"),
+		"/bad.txt":           body,
+		"/bad.txt.sha256":    []byte(HelpSHA256(nil) + "  bad.txt\n"),
+		"/bad.txt.minisig":   k.Sign("ED", []byte("other"), ""),
+	})
+	f := NewFetcher()
+	ctx := context.Background()
+
+	tt := []struct {
+		name    string
+		url     string
+		in      Integrity
+		wantErr error
+	}{
+		{"Pin", "/hosts.txt", Integrity{SHA256: HelpSHA256(body)}, nil},
+		{"Detached", "/hosts.txt", Integrity{SHA256URL: ts.URL + "/hosts.txt.sha256"}, nil},
+		{"Signature", "/hosts.txt", Integrity{PublicKeys: []*MinisignPublicKey{pub}}, nil},
+		{"PinMismatch", "/hosts.txt", Integrity{SHA256: HelpSHA256(nil)}, &ChecksumError{}},
+		{"DetachedMismatch", "/bad.txt", Integrity{SHA256URL: ts.URL + "/bad.txt.sha256"}, &ChecksumError{}},
+		{"SignatureMismatch", "/bad.txt", Integrity{PublicKeys: []*MinisignPublicKey{pub}}, ErrSignatureMismatch},
+	}
+
+	for _, tc := range tt {
+		got, err := f.FetchVerified(ctx, ts.URL+tc.url, tc.in)
+		if tc.wantErr == nil {
+			if err != nil || string(got) != string(body) {
+				t.Errorf("%s: expected content, got (%q, %v)", tc.name, got, err)
+			}
+			continue
+		}
+
+		var inErr *IntegrityError
+		if !errors.As(err, &inErr) || got != nil {
+			t.Errorf("%s: expected *IntegrityError and no content, got (%q, %#v)", tc.name, got, err)
+			continue
+		}
+		switch want := tc.wantErr.(type) {
+		case *ChecksumError:
+			if !errors.As(err, &want) {
+				t.Errorf("%s: expected *ChecksumError, got %#v", tc.name, inErr.Err)
+			}
+		default:
+			if !errors.Is(err, want) {
+				t.Errorf("%s: expected %v, got %v", tc.name, want, inErr.Err)
+			}
+		}
+	}
+}
+
+func TestFetcherMaxSize(t *testing.T) {
+	body := []byte(strings.Repeat("0.0.0.0 example.com\n", 100))
+	ts := HelpFileServer(t, map[string][]byte{"/hosts.txt": body})
+	chunked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for range 100 {
+			w.Write([]byte("0.0.0.0 example.com\n"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer chunked.Close()
+
+	for _, u := range []string{ts.URL + "/hosts.txt", chunked.URL} {
+		f := NewFetcher()
+		f.SetMaxSize(int64(len(body)))
+		got, err := f.Fetch(context.Background(), u)
+		if err != nil || len(got) != len(body) {
+			t.Errorf("%s at limit: expected content, got (%d bytes, %v)", u, len(got), err)
+		}
+
+		f.SetMaxSize(int64(len(body)) - 1)
+		_, err = f.Fetch(context.Background(), u)
+		var sizeErr *FetchSizeError
+		if !errors.As(err, &sizeErr) || sizeErr.Limit != int64(len(body))-1 {
+			t.Errorf("%s over limit: expected *FetchSizeError, got %#v", u, err)
+		}
+	}
+}
+
+func TestFetcherTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("example.com\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	tt := []struct {
+		name     string
+		set      func(f *Fetcher)
+		wantIdle bool
+	}{
+		{"Total", func(f *Fetcher) { f.SetTimeout(50 * time.Millisecond) }, false},
+		{"Idle", func(f *Fetcher) { f.SetIdleTimeout(50 * time.Millisecond) }, true},
+	}
+
+	for _, tc := range tt {
+		f := NewFetcher()
+		tc.set(f)
+		_, err := f.Fetch(context.Background(), ts.URL)
+		var timeoutErr *FetchTimeoutError
+		if !errors.As(err, &timeoutErr) || timeoutErr.Idle != tc.wantIdle {
+			t.Errorf("%s: expected *FetchTimeoutError with Idle %t, got %#v", tc.name, tc.wantIdle, err)
+		}
+	}
+}
+
+func TestFetcherIdleTimeoutProgress(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for range 5 {
+			w.Write([]byte("example.com\n"))
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+
+	f := NewFetcher()
+	f.SetIdleTimeout(60 * time.Millisecond)
+	got, err := f.Fetch(context.Background(), ts.URL)
+	if err != nil || len(got) != 5*len("example.com\n") {
+		t.Errorf("expected content, got (%q, %v)", got, err)
+	}
+}
+
+func TestFetcherSchemes(t *testing.T) {
+	ts := HelpFileServer(t, map[string][]byte{"/hosts.txt": []byte("example.com\n")})
+	redirect := httptest.NewServer(http.RedirectHandler("ftp://example.com/hosts.txt", http.StatusFound))
+	defer redirect.Close()
+
+	f := NewFetcher()
+	for _, u := range []string{"file:///etc/hosts", "gopher://example.com/", redirect.URL} {
+		_, err := f.Fetch(context.Background(), u)
+		var schemeErr *FetchSchemeError
+		if !errors.As(err, &schemeErr) {
+			t.Errorf("%s: expected *FetchSchemeError, got %#v", u, err)
+		}
+	}
+
+	f.SetSchemes("https")
+	_, err := f.Fetch(context.Background(), ts.URL+"/hosts.txt")
+	var schemeErr *FetchSchemeError
+	if !errors.As(err, &schemeErr) || schemeErr.Scheme != "http" {
+		t.Errorf("http with https only: expected *FetchSchemeError, got %#v", err)
+	}
+}
+
+func TestFetcherRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/r/{n}", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.PathValue("n"))
+		if n <= 0 {
+			w.Write([]byte("example.com\n"))
+			return
+		}
+		http.Redirect(w, r, "/r/"+strconv.Itoa(n-1), http.StatusFound)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	f := NewFetcher()
+	f.SetMaxRedirects(2)
+
+	_, err := f.Fetch(context.Background(), ts.URL+"/r/2")
+	if err != nil {
+		t.Errorf("2 redirects: expected no error, got %v", err)
+	}
+
+	_, err = f.Fetch(context.Background(), ts.URL+"/r/3")
+	var redirectErr *FetchRedirectError
+	if !errors.As(err, &redirectErr) || redirectErr.Limit != 2 {
+		t.Errorf("3 redirects: expected *FetchRedirectError, got %#v", err)
+	}
+}
+
+func TestFetcherDenyPrivate(t *testing.T) {
+	ts := HelpFileServer(t, map[string][]byte{"/hosts.txt": []byte("example.com\n")})
+
+	f := NewFetcher()
+	f.SetDenyPrivate(true)
+	_, err := f.Fetch(context.Background(), ts.URL+"/hosts.txt")
+	var addrErr *FetchAddressError
+	if !errors.As(err, &addrErr) || addrErr.Addr != netip.MustParseAddr("127.0.0.1") {
+		t.Errorf("loopback: expected *FetchAddressError, got %#v", err)
+	}
+
+	f.SetDenyPrivate(false)
+	_, err = f.Fetch(context.Background(), ts.URL+"/hosts.txt")
+	if err != nil {
+		t.Errorf("allowed: expected no error, got %v", err)
+	}
+}
+
+func TestFetcherDenyPrivateRedirect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("listen on 127.0.0.2: %v", err)
+	}
+	inner := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret\n"))
+	}))
+	inner.Listener.Close()
+	inner.Listener = ln
+	inner.Start()
+	defer inner.Close()
+
+	outer := httptest.NewServer(http.RedirectHandler(inner.URL+"/", http.StatusFound))
+	defer outer.Close()
+
+	f := NewFetcher()
+	f.SetDenyPrivate(true)
+	f.denied = func(ip netip.Addr) bool { return ip == netip.MustParseAddr("127.0.0.2") }
+
+	_, err = f.Fetch(context.Background(), outer.URL)
+	var addrErr *FetchAddressError
+	if !errors.As(err, &addrErr) || addrErr.Addr != netip.MustParseAddr("127.0.0.2") {
+		t.Errorf("expected *FetchAddressError for the redirect target, got %#v", err)
+	}
+}
+
+func TestIsPrivateAddr(t *testing.T) {
+	tt := []struct {
+		in   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"0.1.2.3", true},
+		{"198.18.0.1", true},
+		{"198.19.255.255", true},
+		{"198.20.0.1", false},
+		{"64:ff9b::7f00:1", true},
+		{"64:ff9b::a00:1", true},
+		{"64:ff9b::5db8:d822", false},
+		{"2002:7f00:1::1", true},
+		{"2002:c0a8:101::1", true},
+		{"2002:5db8:d822::1", false},
+		{"::1", true},
+		{"fc00::1", true},
+		{"fe80::1", true},
+		{"93.184.216.34", false},
+		{"2606:2800:220:1::1", false},
+	}
+
+	for _, tc := range tt {
+		got := isPrivateAddr(netip.MustParseAddr(tc.in))
+		if got != tc.want {
+			t.Errorf("%s: expected %t, got %t", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestFetcherDenyPrivateTransport(t *testing.T) {
+	ts := HelpFileServer(t, map[string][]byte{"/hosts.txt": []byte("example.com\n")})
+
+	f := NewFetcher()
+	f.SetDenyPrivate(true)
+	f.denied = func(netip.Addr) bool { return false }
+	tr := f.transport
+	if tr == nil {
+		t.Fatal("expected a transport after SetDenyPrivate")
+	}
+	if tr.Proxy != nil {
+		t.Error("expected the proxy to be bypassed by default")
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := f.Fetch(context.Background(), ts.URL+"/hosts.txt")
+		if err != nil {
+			t.Fatalf("fetch %d: expected no error, got %v", i, err)
+		}
+		if f.transport != tr {
+			t.Errorf("fetch %d: expected the transport to be reused", i)
+		}
+	}
+
+	f.SetProxy(true)
+	if f.transport == tr {
+		t.Error("SetProxy: expected a new transport")
+	}
+	if f.transport.Proxy == nil {
+		t.Error("SetProxy: expected the client's proxy to be kept")
+	}
+
+	f.SetDenyPrivate(false)
+	if f.transport != nil {
+		t.Error("SetDenyPrivate(false): expected no transport")
+	}
+}
+
+func TestFetcherTransportUnsupported(t *testing.T) {
+	f := NewFetcher()
+	f.SetClient(&http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("unreachable")
+	})})
+	f.SetDenyPrivate(true)
+
+	_, err := f.Fetch(context.Background(), "http://example.com/")
+	if err != ErrTransportUnsupported {
+		t.Errorf("expected ErrTransportUnsupported, got %v", err)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,172 @@
+package filter
+
+import (
+	"bytes"
+	"iter"
+	"slices"
+	"sort"
+	"unsafe"
+)
+
+// DomainSet stores names label-reversed ("com.example.ads") in one sorted
+// arena. Inserts are appended after the sorted entries and merged into them
+// on the next lookup, so a DomainSet must not be read concurrently right
+// after an Insert. Each merge copies the arena; batch inserts before lookups
+// rather than alternating them.
+type DomainSet struct {
+	arena []byte
+	offs  []uint32
+	n     int
+}
+
+// NewDomainSet returns an empty DomainSet.
+func NewDomainSet() *DomainSet {
+	return &DomainSet{}
+}
+
+func (s *DomainSet) Insert(domain string) {
+	s.offs = append(s.offs, uint32(len(s.arena)))
+	s.arena = appendReversed(s.arena, domain)
+}
+
+func (s *DomainSet) Len() int {
+	s.compact()
+	return len(s.offs)
+}
+
+func (s *DomainSet) Contains(domain string) bool {
+	s.compact()
+	var buf [256]byte
+	key := appendReversed(buf[:0], domain)
+	_, ok := s.search(key)
+	return ok
+}
+
+func (s *DomainSet) Match(domain string) bool {
+	s.compact()
+	var buf [256]byte
+	key := appendReversed(buf[:0], domain)
+	for {
+		if _, ok := s.search(key); ok {
+			return true
+		}
+
+		i := bytes.LastIndexByte(key, '.')
+		if i < 0 {
+			return false
+		}
+		key = key[:i]
+	}
+}
+
+func (s *DomainSet) Under(suffix string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		s.compact()
+		key := appendReversed(nil, suffix)
+		if i, ok := s.search(key); ok {
+			if !yield(s.name(i)) {
+				return
+			}
+		}
+
+		key = append(key, '.')
+		i, _ := s.search(key)
+		for ; i < len(s.offs) && bytes.HasPrefix(s.entry(i), key); i++ {
+			if !yield(s.name(i)) {
+				return
+			}
+		}
+	}
+}
+
+func (s *DomainSet) All() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		s.compact()
+		for i := range s.offs {
+			if !yield(s.name(i)) {
+				return
+			}
+		}
+	}
+}
+
+func (s *DomainSet) Size() int {
+	return int(unsafe.Sizeof(*s)) + cap(s.arena) + cap(s.offs)*int(unsafe.Sizeof(uint32(0)))
+}
+
+func (s *DomainSet) entry(i int) []byte {
+	end := len(s.arena)
+	if i+1 < len(s.offs) {
+		end = int(s.offs[i+1])
+	}
+	return s.arena[s.offs[i]:end]
+}
+
+func (s *DomainSet) name(i int) string {
+	e := s.entry(i)
+	return string(appendReversed(make([]byte, 0, len(e)), e))
+}
+
+func (s *DomainSet) search(key []byte) (int, bool) {
+	i := sort.Search(len(s.offs), func(i int) bool {
+		return bytes.Compare(s.entry(i), key) >= 0
+	})
+	return i, i < len(s.offs) && bytes.Equal(s.entry(i), key)
+}
+
+// compact sorts the entries appended since the last lookup and merges them
+// into the sorted prefix, dropping duplicates.
+func (s *DomainSet) compact() {
+	if s.n == len(s.offs) {
+		return
+	}
+
+	tail := make([]int, len(s.offs)-s.n)
+	for i := range tail {
+		tail[i] = s.n + i
+	}
+	slices.SortFunc(tail, func(a, b int) int {
+		return bytes.Compare(s.entry(a), s.entry(b))
+	})
+
+	arena := make([]byte, 0, len(s.arena))
+	offs := make([]uint32, 0, len(s.offs))
+	var prev []byte
+	i, j := 0, 0
+	for i < s.n || j < len(tail) {
+		var e []byte
+		if j >= len(tail) || (i < s.n && bytes.Compare(s.entry(i), s.entry(tail[j])) <= 0) {
+			e = s.entry(i)
+			i++
+		} else {
+			e = s.entry(tail[j])
+			j++
+		}
+		if len(offs) > 0 && bytes.Equal(e, prev) {
+			continue
+		}
+		offs = append(offs, uint32(len(arena)))
+		arena = append(arena, e...)
+		prev = e
+	}
+
+	s.arena = arena
+	s.offs = slices.Clip(offs)
+	s.n = len(s.offs)
+}
+
+func appendReversed[S ~string | ~[]byte](dst []byte, domain S) []byte {
+	if len(domain) > 0 && domain[len(domain)-1] == '.' {
+		domain = domain[:len(domain)-1]
+	}
+
+	end := len(domain)
+	for i := end - 1; i >= 0; i-- {
+		if domain[i] == '.' {
+			dst = append(dst, domain[i+1:end]...)
+			dst = append(dst, '.')
+			end = i
+		}
+	}
+	return append(dst, domain[:end]...)
+}
@@ -0,0 +1,181 @@
+package filter
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func HelpDomainSet() *DomainSet {
+	s := NewDomainSet()
+	for _, d := range []string{"ads.example.com", "example.org", "tracker.example.com", "example.com", "ads.example.com", "example-x.com"} {
+		s.Insert(d)
+	}
+	return s
+}
+
+func TestDomainSetContains(t *testing.T) {
+	s := HelpDomainSet()
+
+	tt := []struct {
+		in   string
+		want bool
+	}{
+		{in: "example.com", want: true},
+		{in: "example.com.", want: true},
+		{in: "ads.example.com", want: true},
+		{in: "www.example.com", want: false},
+		{in: "com", want: false},
+		{in: "example-x.com", want: true},
+		{in: "", want: false},
+	}
+
+	for _, tc := range tt {
+		got := s.Contains(tc.in)
+		if got != tc.want {
+			t.Errorf("%q: expected %t, got %t", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestDomainSetMatch(t *testing.T) {
+	s := NewDomainSet()
+	s.Insert("example.com")
+	s.Insert("ads.example.org")
+
+	tt := []struct {
+		in   string
+		want bool
+	}{
+		{in: "example.com", want: true},
+		{in: "www.example.com", want: true},
+		{in: "a.b.example.com", want: true},
+		{in: "notexample.com", want: false},
+		{in: "example.org", want: false},
+		{in: "x.ads.example.org", want: true},
+	}
+
+	for _, tc := range tt {
+		got := s.Match(tc.in)
+		if got != tc.want {
+			t.Errorf("%q: expected %t, got %t", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestDomainSetAll(t *testing.T) {
+	s := HelpDomainSet()
+
+	got := slices.Collect(s.All())
+	want := []string{"example.com", "example-x.com", "ads.example.com", "tracker.example.com", "example.org"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if s.Len() != len(want) {
+		t.Errorf("s.Len(): expected %d, got %d", len(want), s.Len())
+	}
+}
+
+func TestDomainSetInsertAfterLookup(t *testing.T) {
+	s := HelpDomainSet()
+	s.Len()
+
+	for _, d := range []string{"ads.example.org", "example.com", "a.example.com", "zz.example.net"} {
+		s.Insert(d)
+		if !s.Contains(d) {
+			t.Errorf("%q: expected to be found right after Insert", d)
+		}
+	}
+
+	got := slices.Collect(s.All())
+	want := []string{"example.com", "example-x.com", "a.example.com", "ads.example.com", "tracker.example.com", "zz.example.net", "example.org", "ads.example.org"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestDomainSetUnder(t *testing.T) {
+	s := HelpDomainSet()
+
+	got := slices.Collect(s.Under("example.com"))
+	want := []string{"example.com", "ads.example.com", "tracker.example.com"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	got = slices.Collect(s.Under("sub.example.org"))
+	if len(got) != 0 {
+		t.Errorf("expected no names, got %v", got)
+	}
+}
+
+func TestDomainSetSize(t *testing.T) {
+	s := NewDomainSet()
+	empty := s.Size()
+	s.Insert("example.com")
+	if s.Size() <= empty {
+		t.Errorf("s.Size(): expected more than %d after insert, got %d", empty, s.Size())
+	}
+}
+
+func HelpBenchDomains(n int) []string {
+	ds := make([]string, n)
+	for i := range ds {
+		ds[i] = "host" + strconv.Itoa(i) + ".tracker" + strconv.Itoa(i%1000) + ".example.com"
+	}
+	return ds
+}
+
+func BenchmarkDomainSetInsert(b *testing.B) {
+	ds := HelpBenchDomains(1_000_000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		s := NewDomainSet()
+		for _, d := range ds {
+			s.Insert(d)
+		}
+		s.Len()
+		b.ReportMetric(float64(s.Size())/float64(len(ds)), "bytes/domain")
+	}
+}
+
+func BenchmarkMapInsert(b *testing.B) {
+	ds := HelpBenchDomains(1_000_000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		m := map[string]struct{}{}
+		for _, d := range ds {
+			m[string([]byte(d))] = struct{}{}
+		}
+	}
+}
+
+func BenchmarkDomainSetContains(b *testing.B) {
+	ds := HelpBenchDomains(1_000_000)
+	s := NewDomainSet()
+	for _, d := range ds {
+		s.Insert(d)
+	}
+	s.Len()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		s.Contains(ds[i%len(ds)])
+	}
+}
+
+func BenchmarkMapContains(b *testing.B) {
+	ds := HelpBenchDomains(1_000_000)
+	m := map[string]struct{}{}
+	for _, d := range ds {
+		m[d] = struct{}{}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		_ = m[ds[i%len(ds)]]
+	}
+}
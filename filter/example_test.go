@@ -0,0 +1,75 @@
+package filter_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+func ExampleHostsLoader() {
+	r := strings.NewReader("" +
+		"127.0.0.1 localhost\n" +
+		"0.0.0.0   ads.example.com tracker.example.com\n" +
+		"192.168.0.1 router.example.com\n")
+
+	l := filter.NewHostsLoader(r)
+	l.SetName("hosts.txt")
+	for l.Load() {
+		if err := l.Err(); err != nil {
+			fmt.Println("error:", err)
+			continue
+		}
+		fmt.Println(l.Filter().Domain)
+	}
+	if err := l.Err(); err != nil {
+		fmt.Println("read error:", err)
+	}
+	// Output:
+	// localhost
+	// ads.example.com
+	// tracker.example.com
+	// error: hosts.txt:3:1: 192.168.0.1 is neither a loopback address nor an unspecified address
+}
+
+func ExampleSimpleLoader() {
+	r := strings.NewReader("# allowlist\ngood.example.com\nお名前.com\n")
+
+	l := filter.NewSimpleLoader(r)
+	l.SetName("allow.txt")
+	l.SetException(true)
+	for l.Load() {
+		f := l.Filter()
+		fmt.Println(f.Source(), f.Exception, f.Domain)
+	}
+	// Output:
+	// allow.txt:2 true good.example.com
+	// allow.txt:3 true xn--t8jx73hngb.com
+}
+
+func ExampleIDNAToASCII() {
+	s, err := filter.IDNAToASCII("お名前.com")
+	fmt.Println(s, err)
+
+	_, err = filter.IDNAToASCII("--.com")
+	fmt.Println(err != nil)
+	// Output:
+	// xn--t8jx73hngb.com <nil>
+	// true
+}
+
+func ExampleMatcher() {
+	m := filter.NewMatcher()
+	m.Add(filter.Filter{Domain: "example.com"})
+	m.Add(filter.Filter{Exception: true, Domain: "good.example.com"})
+	m.Add(filter.Filter{Kind: filter.KindRegex, Pattern: `^ad[0-9]*\.`})
+
+	for _, name := range []string{"www.example.com", "good.example.com", "ad12.example.org", "example.org"} {
+		fmt.Println(name, m.Match(name))
+	}
+	// Output:
+	// www.example.com true
+	// good.example.com false
+	// ad12.example.org true
+	// example.org false
+}
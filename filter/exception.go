@@ -0,0 +1,24 @@
+package filter
+
+// ExceptionLoader marks every filter read from another Loader as an exception.
+type ExceptionLoader struct {
+	l Loader
+	f Filter
+}
+
+// NewExceptionLoader returns an ExceptionLoader that reads from l.
+func NewExceptionLoader(l Loader) *ExceptionLoader {
+	return &ExceptionLoader{l: l}
+}
+
+func (l *ExceptionLoader) Load() bool {
+	ok := l.l.Load()
+	l.f = l.l.Filter()
+	if l.f != (Filter{}) {
+		l.f.Exception = true
+	}
+	return ok
+}
+
+func (l *ExceptionLoader) Filter() Filter { return l.f }
+func (l *ExceptionLoader) Err() error     { return l.l.Err() }
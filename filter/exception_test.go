@@ -0,0 +1,41 @@
+package filter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExceptionLoader(t *testing.T) {
+	r := strings.NewReader("0.0.0.0 1.example.com\n192.168.0.1 2.example.com\n0.0.0.0 --.com\n")
+	hl := NewHostsLoader(r)
+	hl.SetName("allow.txt")
+	l := NewExceptionLoader(hl)
+
+	HelpLoaderTest(t, l, true, Filter{Exception: true, Domain: "1.example.com", Name: "allow.txt", Line: 1}, false)
+	HelpLoaderTest(t, l, true, Filter{}, true)
+	HelpResourceErrorTest(t, "l.Err()", l.Err(), "allow.txt", 2)
+	HelpLoaderTest(t, l, true, Filter{Exception: true, Domain: "--.com", Name: "allow.txt", Line: 3}, true)
+	HelpResourceErrorTest(t, "l.Err()", l.Err(), "allow.txt", 3)
+	if resErr := l.Err().(*ResourceError); resErr.Column != 9 || resErr.Text != "0.0.0.0 --.com" {
+		t.Errorf("l.Err(): expected column 9 and the line text, got %d and %q", resErr.Column, resErr.Text)
+	}
+	HelpLoaderTest(t, l, false, Filter{}, false)
+}
+
+func TestExceptionLoaderRegex(t *testing.T) {
+	l := NewExceptionLoader(NewRegexLoader(strings.NewReader("^good\\.\n")))
+
+	HelpLoaderTest(t, l, true, Filter{Exception: true, Kind: KindRegex, Pattern: "^good\\.", Line: 1}, false)
+	HelpLoaderTest(t, l, false, Filter{}, false)
+}
+
+func TestExceptionLoaderReadError(t *testing.T) {
+	mockErr := errors.New("test")
+	l := NewExceptionLoader(NewHostsLoader(&ErrorReader{Err: mockErr}))
+
+	HelpLoaderTest(t, l, false, Filter{}, true)
+	if l.Err() != mockErr {
+		t.Errorf("l.Err(): expected %#v, got %#v", mockErr, l.Err())
+	}
+}
@@ -0,0 +1,268 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+// HomographReason tells why a domain was flagged as a possible homograph.
+type HomographReason string
+
+const (
+	ReasonMixedScript HomographReason = "mixed scripts"
+	ReasonConfusable  HomographReason = "confusable with a protected name"
+)
+
+// HomographAnalyzer flags internationalized domains that mix scripts or
+// look like a protected name.
+type HomographAnalyzer struct {
+	protected []protectedName
+}
+
+type protectedName struct {
+	name     string
+	skeleton string
+}
+
+// NewHomographAnalyzer returns an analyzer that protects the given names.
+// It fails if a protected name is not a valid domain.
+func NewHomographAnalyzer(protected ...string) (*HomographAnalyzer, error) {
+	a := &HomographAnalyzer{}
+	for _, name := range protected {
+		ascii, err := IDNAToASCII(name)
+		if err != nil {
+			return nil, err
+		}
+		name, err := IDNAToUnicode(ascii)
+		if err != nil {
+			return nil, err
+		}
+		a.protected = append(a.protected, protectedName{
+			name:     name,
+			skeleton: Skeleton(name),
+		})
+	}
+	return a, nil
+}
+
+// Check looks for mixed scripts in internationalized names, and compares
+// the skeleton of every name, ASCII or not, against the protected names so
+// that ASCII look-alikes such as "paypa1.com" are caught too.
+func (a *HomographAnalyzer) Check(f Filter) []HomographWarning {
+	if f.Kind != KindDomain {
+		return nil
+	}
+
+	u, err := IDNAToUnicode(f.Domain)
+	if err != nil {
+		return nil
+	}
+
+	var ws []HomographWarning
+	warn := func(reason HomographReason, label, protected string) {
+		ws = append(ws, HomographWarning{
+			Domain:    f.Domain,
+			Label:     label,
+			Reason:    reason,
+			Protected: protected,
+			Name:      f.Name,
+			Line:      f.Line,
+		})
+	}
+
+	for _, label := range strings.Split(u, ".") {
+		if isASCII(label) {
+			continue
+		}
+		if scripts := LabelScripts(label); isMixedScript(scripts) {
+			warn(ReasonMixedScript, label, "")
+		}
+	}
+
+	sk := Skeleton(u)
+	for _, p := range a.protected {
+		if u == p.name || strings.HasSuffix(u, "."+p.name) {
+			continue
+		}
+		if sk == p.skeleton || strings.HasSuffix(sk, "."+p.skeleton) {
+			warn(ReasonConfusable, "", p.name)
+		}
+	}
+	return ws
+}
+
+func LabelScripts(label string) []string {
+	var scripts []string
+	for _, r := range label {
+		name := runeScript(r)
+		if name == "" {
+			continue
+		}
+		if !contains(scripts, name) {
+			scripts = append(scripts, name)
+		}
+	}
+	return scripts
+}
+
+func runeScript(r rune) string {
+	if r < utf8.RuneSelf {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' {
+			return "Latin"
+		}
+		return ""
+	}
+	if unicode.In(r, unicode.Common, unicode.Inherited) {
+		return ""
+	}
+	for _, s := range scriptTable {
+		if unicode.Is(s.table, r) {
+			return s.name
+		}
+	}
+	return "Other"
+}
+
+// scriptTable lists the scripts LabelScripts tells apart, most frequent in
+// domain names first. Runes of any other script are reported as "Other".
+var scriptTable = []struct {
+	name  string
+	table *unicode.RangeTable
+}{
+	{"Latin", unicode.Latin},
+	{"Han", unicode.Han},
+	{"Hiragana", unicode.Hiragana},
+	{"Katakana", unicode.Katakana},
+	{"Hangul", unicode.Hangul},
+	{"Cyrillic", unicode.Cyrillic},
+	{"Greek", unicode.Greek},
+	{"Arabic", unicode.Arabic},
+	{"Hebrew", unicode.Hebrew},
+	{"Thai", unicode.Thai},
+	{"Devanagari", unicode.Devanagari},
+	{"Armenian", unicode.Armenian},
+	{"Georgian", unicode.Georgian},
+	{"Bopomofo", unicode.Bopomofo},
+	{"Cherokee", unicode.Cherokee},
+}
+
+var allowedScriptSets = [][]string{
+	{"Latin", "Han", "Hiragana", "Katakana"},
+	{"Latin", "Han", "Bopomofo"},
+	{"Latin", "Han", "Hangul"},
+}
+
+func isMixedScript(scripts []string) bool {
+	if len(scripts) <= 1 {
+		return false
+	}
+
+next:
+	for _, allowed := range allowedScriptSets {
+		for _, s := range scripts {
+			if !contains(allowed, s) {
+				continue next
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func contains(ss []string, s string) bool {
+	for _, t := range ss {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Skeleton follows the UTS #39 skeleton algorithm with a built-in subset
+// of the confusables table that covers the common Latin look-alikes.
+func Skeleton(s string) string {
+	s = norm.NFD.String(strings.ToLower(s))
+
+	var b strings.Builder
+	for _, r := range s {
+		if t, ok := confusables[r]; ok {
+			b.WriteString(t)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return norm.NFD.String(b.String())
+}
+
+var confusables = map[rune]string{
+	// Cyrillic
+	'а': "a", 'е': "e", 'ѕ': "s", 'і': "i", 'ј': "j", 'һ': "h", 'о': "o",
+	'р': "p", 'с': "c", 'у': "y", 'х': "x", 'ԁ': "d", 'ԛ': "q", 'ԝ': "w",
+	'ӏ': "l", 'ү': "y",
+	// Greek
+	'α': "a", 'ε': "e", 'ι': "i", 'κ': "k", 'ν': "v", 'ο': "o", 'ρ': "p",
+	'υ': "u", 'χ': "x", 'γ': "y",
+	// Latin and digits
+	'ı': "i", 'ɩ': "i", 'ɡ': "g", 'ℓ': "l", '0': "o", '1': "l", 'm': "rn",
+	// Armenian
+	'օ': "o", 'ս': "u", 'հ': "h", 'ց': "g",
+}
+
+// HomographWarning describes one domain flagged by a HomographAnalyzer.
+type HomographWarning struct {
+	Domain    string
+	Label     string
+	Reason    HomographReason
+	Protected string
+	Name      string
+	Line      int
+}
+
+func (w HomographWarning) String() string {
+	return w.Format(DisplayBoth)
+}
+
+// Format describes the warning with the domain shown in mode. The Unicode
+// forms are always quoted, which escapes non-graphic and bidi control runes.
+func (w HomographWarning) Format(mode DisplayMode) string {
+	b := []byte(Filter{Name: w.Name, Line: w.Line}.Source())
+	if len(b) > 0 {
+		b = append(b, ": "...)
+	}
+
+	b = append(b, "domain "...)
+	u, err := IDNAToUnicode(w.Domain)
+	switch {
+	case mode == DisplayASCII || err != nil || u == w.Domain:
+		b = strconv.AppendQuote(b, w.Domain)
+	case mode == DisplayUnicode:
+		b = strconv.AppendQuote(b, u)
+	default:
+		b = strconv.AppendQuote(b, u)
+		b = append(b, " ("...)
+		b = append(b, w.Domain...)
+		b = append(b, ')')
+	}
+	b = append(b, ": "...)
+	b = append(b, w.Reason...)
+	switch {
+	case w.Protected != "":
+		b = append(b, ' ')
+		b = strconv.AppendQuote(b, w.Protected)
+	case w.Label != "":
+		label := w.Label
+		if mode == DisplayASCII {
+			if a, err := idna.Punycode.ToASCII(label); err == nil {
+				label = a
+			}
+		}
+		b = append(b, " in label "...)
+		b = strconv.AppendQuote(b, label)
+	}
+	return string(b)
+}
@@ -0,0 +1,191 @@
+package filter
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func HelpHomographAnalyzer(t *testing.T, protected ...string) *HomographAnalyzer {
+	t.Helper()
+	a, err := NewHomographAnalyzer(protected...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func HelpIDN(t *testing.T, s string) string {
+	t.Helper()
+	a, err := IDNAToASCII(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestHomographAnalyzerCheck(t *testing.T) {
+	a := HelpHomographAnalyzer(t, "example.com", "お名前.com")
+
+	spoof := HelpIDN(t, "exаmple.com")
+	ws := a.Check(Filter{Exception: true, Domain: spoof, Name: "allow.txt", Line: 7})
+	if len(ws) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", ws)
+	}
+
+	if ws[0].Reason != ReasonMixedScript || ws[0].Label != "exаmple" {
+		t.Errorf("ws[0]: expected mixed scripts in %q, got %v", "exаmple", ws[0])
+	}
+	if ws[1].Reason != ReasonConfusable || ws[1].Protected != "example.com" {
+		t.Errorf("ws[1]: expected confusable with %q, got %v", "example.com", ws[1])
+	}
+	if ws[1].Name != "allow.txt" || ws[1].Line != 7 || ws[1].Domain != spoof {
+		t.Errorf("ws[1]: unexpected provenance %#v", ws[1])
+	}
+}
+
+func TestHomographAnalyzerCheckSubdomain(t *testing.T) {
+	a := HelpHomographAnalyzer(t, "example.com")
+	ws := a.Check(Filter{Domain: HelpIDN(t, "login.ехаmple.com")})
+	if len(ws) != 2 || ws[1].Reason != ReasonConfusable {
+		t.Errorf("expected a confusable warning, got %v", ws)
+	}
+}
+
+func TestHomographAnalyzerCheckClean(t *testing.T) {
+	a := HelpHomographAnalyzer(t, "example.com")
+	for _, d := range []string{"example.com", "www.example.com", "other.org", HelpIDN(t, "お名前.com"), HelpIDN(t, "日本語テスト.jp"), HelpIDN(t, "www.お名前.example.com")} {
+		if ws := a.Check(Filter{Domain: d}); len(ws) != 0 {
+			t.Errorf("%q: expected no warnings, got %v", d, ws)
+		}
+	}
+}
+
+func TestHomographAnalyzerCheckASCII(t *testing.T) {
+	a := HelpHomographAnalyzer(t, "paypal.com")
+
+	tt := []struct {
+		in   string
+		want int
+	}{
+		{in: "paypa1.com", want: 1},
+		{in: "login.paypa1.com", want: 1},
+		{in: "paypal.com", want: 0},
+		{in: "paypal.org", want: 0},
+	}
+
+	for _, tc := range tt {
+		ws := a.Check(Filter{Domain: tc.in})
+		if len(ws) != tc.want {
+			t.Errorf("%q: expected %d warnings, got %v", tc.in, tc.want, ws)
+			continue
+		}
+		if tc.want > 0 && (ws[0].Reason != ReasonConfusable || ws[0].Protected != "paypal.com") {
+			t.Errorf("%q: expected confusable with %q, got %v", tc.in, "paypal.com", ws[0])
+		}
+	}
+}
+
+func TestNewHomographAnalyzerError(t *testing.T) {
+	for _, name := range []string{"--.com", "xn--abc.com", "a b.com"} {
+		_, err := NewHomographAnalyzer("example.com", name)
+		var idnaErr *IDNAError
+		if !errors.As(err, &idnaErr) {
+			t.Errorf("%q: expected *IDNAError, got %#v", name, err)
+		}
+	}
+}
+
+func TestLabelScripts(t *testing.T) {
+	tt := []struct {
+		in   string
+		want []string
+	}{
+		{in: "example-1", want: []string{"Latin"}},
+		{in: "exаmple", want: []string{"Latin", "Cyrillic"}},
+		{in: "お名前", want: []string{"Hiragana", "Han"}},
+		{in: "123", want: nil},
+		{in: "aש", want: []string{"Latin", "Hebrew"}},
+		{in: "aᚠ", want: []string{"Latin", "Other"}},
+	}
+
+	for _, tc := range tt {
+		got := LabelScripts(tc.in)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%q: expected %v, got %v", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestSkeleton(t *testing.T) {
+	if Skeleton("ехаmрlе.com") != Skeleton("example.com") {
+		t.Error("expected Cyrillic look-alike to share the skeleton of example.com")
+	}
+	if Skeleton("paypa1.com") != Skeleton("paypal.com") {
+		t.Error("expected digit one to share the skeleton of l")
+	}
+	if Skeleton("example.org") == Skeleton("example.com") {
+		t.Error("expected different names to have different skeletons")
+	}
+}
+
+func TestHomographWarningString(t *testing.T) {
+	w := HomographWarning{
+		Domain:    "xn--exmple-4nf.com",
+		Reason:    ReasonConfusable,
+		Protected: "example.com",
+		Name:      "allow.txt",
+		Line:      7,
+	}
+	const want = `allow.txt:7: domain "exаmple.com" (xn--exmple-4nf.com): confusable with a protected name "example.com"`
+	if got := w.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestHomographWarningFormat(t *testing.T) {
+	tt := []struct {
+		name string
+		in   HomographWarning
+		mode DisplayMode
+		want string
+	}{
+		{
+			name: "ASCII",
+			in:   HomographWarning{Domain: "xn--exmple-4nf.com", Reason: ReasonConfusable, Protected: "example.com"},
+			mode: DisplayASCII,
+			want: `domain "xn--exmple-4nf.com": confusable with a protected name "example.com"`,
+		},
+		{
+			name: "Unicode",
+			in:   HomographWarning{Domain: "xn--exmple-4nf.com", Reason: ReasonConfusable, Protected: "example.com"},
+			mode: DisplayUnicode,
+			want: `domain "exаmple.com": confusable with a protected name "example.com"`,
+		},
+		{
+			name: "LabelASCII",
+			in:   HomographWarning{Domain: "xn--exmple-4nf.com", Reason: ReasonMixedScript, Label: "exаmple"},
+			mode: DisplayASCII,
+			want: `domain "xn--exmple-4nf.com": mixed scripts in label "xn--exmple-4nf"`,
+		},
+		{
+			name: "BidiLabel",
+			in:   HomographWarning{Domain: "xn--exmple-4nf.com", Reason: ReasonMixedScript, Label: "ex\u202eelpma"},
+			mode: DisplayUnicode,
+			want: `domain "exаmple.com": mixed scripts in label "ex\u202eelpma"`,
+		},
+		{
+			name: "ControlProtected",
+			in:   HomographWarning{Domain: "paypa1.com", Reason: ReasonConfusable, Protected: "pay\x00pal.com"},
+			mode: DisplayBoth,
+			want: `domain "paypa1.com": confusable with a protected name "pay\x00pal.com"`,
+		},
+	}
+
+	for _, tc := range tt {
+		got := tc.in.Format(tc.mode)
+		if got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
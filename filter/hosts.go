@@ -0,0 +1,322 @@
+package filter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/netip"
+	"strconv"
+	"unsafe"
+)
+
+var ErrMissingHostname = errors.New("missing hostname field")
+
+// HostsLoader reads filters from a hosts file, one per hostname.
+type HostsLoader struct {
+	p    *HostsParser
+	name string
+	idna *IDNAProfile
+	syn  SyntaxMode
+	exc  bool
+
+	hs []string
+	i  int
+
+	f   Filter
+	err error
+}
+
+// NewHostsLoader returns a HostsLoader that reads from r.
+func NewHostsLoader(r io.Reader) *HostsLoader {
+	p := NewHostsParser(r)
+	return &HostsLoader{p: p}
+}
+
+func (l *HostsLoader) SetIDNAProfile(p *IDNAProfile) {
+	l.idna = p
+}
+
+func (l *HostsLoader) SetSyntaxMode(mode SyntaxMode) {
+	l.syn = mode
+}
+
+func (l *HostsLoader) SetName(name string) {
+	l.name = name
+}
+
+func (l *HostsLoader) SetInterner(in *Interner) {
+	l.p.SetInterner(in)
+}
+
+func (l *HostsLoader) SetException(exc bool) {
+	l.exc = exc
+}
+
+func (l *HostsLoader) Load() bool {
+	if l.i+1 < len(l.hs) {
+		l.i++
+		l.setDomain(l.hs[l.i])
+		return true
+	}
+	l.hs = nil
+	l.i = 0
+
+	for l.p.Parse() {
+		if l.p.Err != nil {
+			if resErr, ok := l.p.Err.(*ResourceError); ok {
+				resErr.Name = l.name
+			}
+
+			l.f = Filter{}
+			l.err = l.p.Err
+			return true
+		}
+
+		if !l.p.IP.IsLoopback() && !l.p.IP.IsUnspecified() {
+			l.f = Filter{}
+			l.err = &ResourceError{
+				Name:   l.name,
+				Line:   l.p.Line,
+				Column: l.p.IPColumn,
+				Text:   l.p.text(),
+				Err:    &HostsIPError{IP: l.p.IP},
+			}
+			return true
+		}
+
+		if len(l.p.Hosts) <= 0 {
+			l.f = Filter{}
+			l.err = &ResourceError{
+				Name:   l.name,
+				Line:   l.p.Line,
+				Column: l.p.ipEnd + 1,
+				Text:   l.p.text(),
+				Err:    ErrMissingHostname,
+			}
+			return true
+		}
+
+		l.hs = l.p.Hosts
+		l.setDomain(l.p.Hosts[0])
+		return true
+	}
+	l.f = Filter{}
+	l.err = l.p.Err
+	return false
+}
+
+func (l *HostsLoader) setDomain(domain string) {
+	domain, err := normalizeDomain(domain, l.syn, l.idna)
+	if err != nil {
+		err = &ResourceError{
+			Name:   l.name,
+			Line:   l.p.Line,
+			Column: l.p.HostColumns[l.i] + syntaxOffset(err),
+			Text:   l.p.text(),
+			Err:    err,
+		}
+	}
+
+	l.f = Filter{
+		Exception: l.exc,
+		Domain:    domain,
+		Name:      l.name,
+		Line:      l.p.Line,
+	}
+	l.err = err
+}
+
+func (l *HostsLoader) Filter() Filter { return l.f }
+func (l *HostsLoader) Err() error     { return l.err }
+
+// HostsParser splits a hosts file into addresses and hostnames without
+// validating the hostnames. Hosts and HostColumns are valid only until the
+// next call to Parse, which reuses their backing arrays; copy them to keep
+// them longer.
+type HostsParser struct {
+	Line        int
+	IP          netip.Addr
+	Hosts       []string
+	IPColumn    int
+	HostColumns []int
+	Err         error
+
+	s      *bufio.Scanner
+	lnum   int
+	fields [][]byte
+	offs   []int
+	ipEnd  int
+	in     *Interner
+}
+
+// NewHostsParser returns a HostsParser that reads from r.
+func NewHostsParser(r io.Reader) *HostsParser {
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanLines)
+	return &HostsParser{s: s, offs: make([]int, 0, 4)}
+}
+
+func (p *HostsParser) SetInterner(in *Interner) {
+	p.in = in
+}
+
+func (p *HostsParser) Parse() bool {
+	for p.s.Scan() {
+		p.lnum++
+
+		line := p.s.Bytes()
+		ip, hs, offs, err := parseHostsLine(line, p.fields, p.offs)
+		p.fields = hs
+		p.offs = offs
+		if err != nil {
+			resErr := &ResourceError{Line: p.lnum, Text: string(line), Err: err}
+			if fieldErr, ok := err.(*HostsFieldError); ok {
+				resErr.Column = fieldErr.Column
+			}
+
+			p.reset()
+			p.Line = p.lnum
+			p.Err = resErr
+			return true
+		}
+		if ip.IsValid() || len(hs) > 0 {
+			p.Line = p.lnum
+			p.IP = ip
+			p.IPColumn = 0
+			p.ipEnd = 0
+			if ip.IsValid() {
+				p.IPColumn = offs[0] + 1
+				p.ipEnd = offs[0] + hostsFieldLen(line[offs[0]:])
+			}
+			p.Hosts = p.Hosts[:0]
+			p.HostColumns = p.HostColumns[:0]
+			for i, h := range hs {
+				p.Hosts = append(p.Hosts, p.in.Intern(h))
+				p.HostColumns = append(p.HostColumns, offs[i+1]+1)
+			}
+			p.Err = nil
+			return true
+		}
+	}
+
+	p.reset()
+	p.Line = p.lnum
+	p.Err = p.s.Err()
+	return false
+}
+
+func (p *HostsParser) reset() {
+	p.IP = netip.Addr{}
+	p.Hosts = nil
+	p.IPColumn = 0
+	p.HostColumns = nil
+	p.ipEnd = 0
+}
+
+func (p *HostsParser) text() string {
+	return string(p.s.Bytes())
+}
+
+func ParseHostsLine(line []byte) (netip.Addr, []string, error) {
+	ip, fields, err := ParseHostsLineBytes(line, nil)
+	if err != nil {
+		return netip.Addr{}, nil, err
+	}
+
+	var hs []string
+	for _, field := range fields {
+		hs = append(hs, string(field))
+	}
+	return ip, hs, nil
+}
+
+func ParseHostsLineBytes(line []byte, hs [][]byte) (netip.Addr, [][]byte, error) {
+	ip, hs, _, err := parseHostsLine(line, hs, nil)
+	return ip, hs, err
+}
+
+// parseHostsLine is ParseHostsLineBytes that also records the byte offset
+// of every field in offs, the address field first. A nil offs records
+// nothing, which keeps ParseHostsLineBytes free of allocations.
+func parseHostsLine(line []byte, hs [][]byte, offs []int) (netip.Addr, [][]byte, []int, error) {
+	var ip netip.Addr
+	hs = hs[:0]
+	offs = offs[:0]
+
+	off := 0
+	fieldIdx := 0
+	for {
+		buf := line[off:]
+		fieldLen := hostsFieldLen(buf)
+		if fieldLen > 0 {
+			field := buf[:fieldLen]
+			if fieldIdx == 0 {
+				var err error
+				ip, err = parseAddr(field)
+				if err != nil {
+					return netip.Addr{}, hs, offs, &HostsFieldError{
+						Field:  fieldIdx + 1,
+						Column: off + 1,
+						Err:    err,
+					}
+				}
+			} else {
+				hs = append(hs, field)
+			}
+			if offs != nil {
+				offs = append(offs, off)
+			}
+			fieldIdx++
+		}
+
+		if fieldLen >= len(buf) || buf[fieldLen] == '#' {
+			break
+		}
+		off += fieldLen + 1
+	}
+	return ip, hs, offs, nil
+}
+
+func hostsFieldLen(b []byte) int {
+	n := bytes.IndexAny(b, " \t#")
+	if n < 0 {
+		return len(b)
+	}
+	return n
+}
+
+func parseAddr(b []byte) (netip.Addr, error) {
+	ip, err := netip.ParseAddr(unsafe.String(unsafe.SliceData(b), len(b)))
+	if err != nil {
+		// The error keeps its input, which must not alias the scanner buffer.
+		return netip.ParseAddr(string(b))
+	}
+	return ip, nil
+}
+
+// HostsIPError reports a hosts line whose address is a valid IP that is
+// neither loopback nor unspecified. An address that does not parse is
+// reported as a HostsFieldError instead.
+type HostsIPError struct {
+	IP netip.Addr
+}
+
+func (e *HostsIPError) Error() string {
+	return e.IP.String() + " is neither a loopback address nor an unspecified address"
+}
+
+// HostsFieldError wraps an error found in one field of a hosts line.
+type HostsFieldError struct {
+	Field  int
+	Column int
+	Err    error
+}
+
+func (e *HostsFieldError) Error() string {
+	return "field " + strconv.Itoa(e.Field) + ": " + e.Err.Error()
+}
+
+func (e *HostsFieldError) Unwrap() error {
+	return e.Err
+}
@@ -1,4 +1,4 @@
-package main
+package filter
 
 import (
 	"bytes"
@@ -6,6 +6,7 @@ import (
 	"net/netip"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -24,13 +25,37 @@ func TestHostsLoaderLoadNormal(t *testing.T) {
 		"0.0.0.0   7.example.com\n"
 	r := strings.NewReader(s)
 	l := NewHostsLoader(r)
-	HelpLoaderTest(t, l, true, Filter{Domain: "1.example.com"}, false)
-	HelpLoaderTest(t, l, true, Filter{Domain: "2.example.com"}, false)
-	HelpLoaderTest(t, l, true, Filter{Domain: "3.example.com"}, false)
-	HelpLoaderTest(t, l, true, Filter{Domain: "4.example.com"}, false)
-	HelpLoaderTest(t, l, true, Filter{Domain: "5.example.com"}, false)
-	HelpLoaderTest(t, l, true, Filter{Domain: "6.example.com"}, false)
-	HelpLoaderTest(t, l, true, Filter{Domain: "7.example.com"}, false)
+	HelpLoaderTest(t, l, true, Filter{Domain: "1.example.com", Line: 1}, false)
+	HelpLoaderTest(t, l, true, Filter{Domain: "2.example.com", Line: 2}, false)
+	HelpLoaderTest(t, l, true, Filter{Domain: "3.example.com", Line: 2}, false)
+	HelpLoaderTest(t, l, true, Filter{Domain: "4.example.com", Line: 2}, false)
+	HelpLoaderTest(t, l, true, Filter{Domain: "5.example.com", Line: 3}, false)
+	HelpLoaderTest(t, l, true, Filter{Domain: "6.example.com", Line: 3}, false)
+	HelpLoaderTest(t, l, true, Filter{Domain: "7.example.com", Line: 4}, false)
+	HelpLoaderTest(t, l, false, Filter{}, false)
+}
+
+func TestHostsLoaderLoadException(t *testing.T) {
+	r := strings.NewReader("0.0.0.0 1.example.com 2.example.com\n192.168.0.1 3.example.com\n")
+	l := NewHostsLoader(r)
+	l.SetException(true)
+	HelpLoaderTest(t, l, true, Filter{Exception: true, Domain: "1.example.com", Line: 1}, false)
+	HelpLoaderTest(t, l, true, Filter{Exception: true, Domain: "2.example.com", Line: 1}, false)
+	HelpLoaderTest(t, l, true, Filter{}, true)
+	HelpResourceErrorTest(t, "l.Err()", l.Err(), "", 2)
+	HelpLoaderTest(t, l, false, Filter{}, false)
+}
+
+func TestHostsLoaderLoadName(t *testing.T) {
+	r := strings.NewReader("127.0.0.1 1.example.com\nexample.com\n192.168.0.1 2.example.com\n")
+	l := NewHostsLoader(r)
+	l.SetName("hosts.txt")
+
+	HelpLoaderTest(t, l, true, Filter{Domain: "1.example.com", Name: "hosts.txt", Line: 1}, false)
+	HelpLoaderTest(t, l, true, Filter{}, true)
+	HelpResourceErrorTest(t, "l.Err()", l.Err(), "hosts.txt", 2)
+	HelpLoaderTest(t, l, true, Filter{}, true)
+	HelpResourceErrorTest(t, "l.Err()", l.Err(), "hosts.txt", 3)
 	HelpLoaderTest(t, l, false, Filter{}, false)
 }
 
@@ -68,7 +93,9 @@ func TestHostsLoaderRedirectError(t *testing.T) {
 	HelpLoaderTest(t, l, true, Filter{}, true)
 
 	wantErr := &ResourceError{
-		Line: 1,
+		Line:   1,
+		Column: 1,
+		Text:   "192.168.0.1 example.com",
 		Err: &HostsIPError{
 			IP: netip.AddrFrom4([4]byte{192, 168, 0, 1}),
 		},
@@ -87,7 +114,7 @@ func TestHostsLoaderNoHostError(t *testing.T) {
 
 	HelpLoaderTest(t, l, true, Filter{}, true)
 
-	wantErr := &ResourceError{Line: 1, Err: ErrMissingHostname}
+	wantErr := &ResourceError{Line: 1, Column: 10, Text: "127.0.0.1", Err: ErrMissingHostname}
 	gotErr := l.Err()
 	if !reflect.DeepEqual(gotErr, wantErr) {
 		t.Errorf("l.Err(): expected %#v, got %#v", wantErr, gotErr)
@@ -99,7 +126,15 @@ func TestHostsLoaderNoHostError(t *testing.T) {
 func TestHostsLoaderIDNANormal(t *testing.T) {
 	r := strings.NewReader("127.0.0.1 お名前.com")
 	l := NewHostsLoader(r)
-	HelpLoaderTest(t, l, true, Filter{Domain: "xn--t8jx73hngb.com"}, false)
+	HelpLoaderTest(t, l, true, Filter{Domain: "xn--t8jx73hngb.com", Line: 1}, false)
+	HelpLoaderTest(t, l, false, Filter{}, false)
+}
+
+func TestHostsLoaderIDNAProfile(t *testing.T) {
+	r := strings.NewReader("0.0.0.0 _tcp.example.com\n")
+	l := NewHostsLoader(r)
+	l.SetIDNAProfile(IDNAUnderscore)
+	HelpLoaderTest(t, l, true, Filter{Domain: "_tcp.example.com", Line: 1}, false)
 	HelpLoaderTest(t, l, false, Filter{}, false)
 }
 
@@ -107,12 +142,41 @@ func TestHostsLoaderIDNAError(t *testing.T) {
 	r := strings.NewReader("127.0.0.1 --.com")
 	l := NewHostsLoader(r)
 
-	HelpLoaderTest(t, l, true, Filter{Domain: "--.com"}, true)
+	HelpLoaderTest(t, l, true, Filter{Domain: "--.com", Line: 1}, true)
 	HelpResourceErrorTest(t, "l.Err()", l.Err(), "", 1)
 
 	HelpLoaderTest(t, l, false, Filter{}, false)
 }
 
+func TestHostsLoaderIDNAErrorColumn(t *testing.T) {
+	tt := []struct {
+		in         string
+		wantColumn int
+	}{
+		{in: "127.0.0.1 example.com --.com", wantColumn: 23},
+		{in: "\t127.0.0.1\t \texample.com  --.com # comment", wantColumn: 27},
+		{in: "127.0.0.1 example.com\t\t--.com", wantColumn: 24},
+	}
+
+	for _, tc := range tt {
+		l := NewHostsLoader(strings.NewReader(tc.in))
+		for l.Load() && l.Err() == nil {
+		}
+
+		resErr, ok := l.Err().(*ResourceError)
+		if !ok {
+			t.Errorf("%q: expected *ResourceError, got %#v", tc.in, l.Err())
+			continue
+		}
+		if resErr.Column != tc.wantColumn {
+			t.Errorf("%q: Column: expected %d, got %d", tc.in, tc.wantColumn, resErr.Column)
+		}
+		if resErr.Text != tc.in {
+			t.Errorf("%q: Text: expected %q, got %q", tc.in, tc.in, resErr.Text)
+		}
+	}
+}
+
 func TestHostsLoaderReadError(t *testing.T) {
 	mockErr := errors.New("test")
 	r := &ErrorReader{Err: mockErr}
@@ -260,6 +324,26 @@ func TestHostsParserParseMultiple(t *testing.T) {
 	}
 }
 
+func TestHostsParserParseReuse(t *testing.T) {
+	p := NewHostsParser(strings.NewReader("0.0.0.0 a.example b.example\n0.0.0.0 c.example d.example\n"))
+
+	if !p.Parse() {
+		t.Fatal("first parse failed")
+	}
+	hosts := p.Hosts
+	kept := slices.Clone(p.Hosts)
+
+	if !p.Parse() {
+		t.Fatal("second parse failed")
+	}
+	if !slices.Equal(kept, []string{"a.example", "b.example"}) {
+		t.Errorf("kept: expected [a.example b.example], got %v", kept)
+	}
+	if !slices.Equal(hosts, []string{"c.example", "d.example"}) {
+		t.Errorf("hosts: expected the second line to reuse the slice, got %v", hosts)
+	}
+}
+
 func TestHostsParserParseError(t *testing.T) {
 	s := "\n\nexample.com"
 	r := strings.NewReader(s)
@@ -279,8 +363,31 @@ func TestHostsParserParseError(t *testing.T) {
 	if err.Line != 3 {
 		t.Errorf("err.Line: expected 3, got %d", err.Line)
 	}
-	if err.Err == nil {
-		t.Error("err.Err: expected any error, got nil")
+	if err.Column != 1 {
+		t.Errorf("err.Column: expected 1, got %d", err.Column)
+	}
+	if err.Text != "example.com" {
+		t.Errorf("err.Text: expected \"example.com\", got %q", err.Text)
+	}
+	var fieldErr *HostsFieldError
+	if !errors.As(err.Err, &fieldErr) || fieldErr.Field != 1 {
+		t.Errorf("err.Err: expected *HostsFieldError for field 1, got %#v", err.Err)
+	}
+}
+
+func TestHostsParserParseColumns(t *testing.T) {
+	r := strings.NewReader("  0.0.0.0\ta.example.com  b.example.com # c")
+	p := NewHostsParser(r)
+
+	if !p.Parse() {
+		t.Fatal("parse failed")
+	}
+	if p.IPColumn != 3 {
+		t.Errorf("p.IPColumn: expected 3, got %d", p.IPColumn)
+	}
+	wantCols := []int{11, 26}
+	if !reflect.DeepEqual(p.HostColumns, wantCols) {
+		t.Errorf("p.HostColumns: expected %v, got %v", wantCols, p.HostColumns)
 	}
 }
 
@@ -387,6 +494,14 @@ func TestParseHostsLine(t *testing.T) {
 	}
 }
 
+func TestHostsFieldErrorError(t *testing.T) {
+	err := &HostsFieldError{Field: 1, Column: 1, Err: errors.New("some error")}
+	want := "field 1: some error"
+	if got := err.Error(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
 func TestHostsIPErrorError(t *testing.T) {
 	err := &HostsIPError{IP: netip.AddrFrom4([4]byte{192, 168, 0, 1})}
 	got := err.Error()
@@ -395,3 +510,97 @@ func TestHostsIPErrorError(t *testing.T) {
 		t.Errorf("expected %q, got %q", want, got)
 	}
 }
+
+func TestParseHostsLineBytesReuse(t *testing.T) {
+	buf := make([][]byte, 0, 4)
+	ip, hs, err := ParseHostsLineBytes([]byte("0.0.0.0 a.example.com b.example.com"), buf)
+	if err != nil {
+		t.Fatalf("err: expected nil, got %#v", err)
+	}
+	if ip != netip.AddrFrom4([4]byte{0, 0, 0, 0}) {
+		t.Errorf("ip: expected 0.0.0.0, got %s", ip)
+	}
+	if len(hs) != 2 || string(hs[0]) != "a.example.com" || string(hs[1]) != "b.example.com" {
+		t.Errorf("hs: expected [a.example.com b.example.com], got %q", hs)
+	}
+	if &hs[0] != &buf[:1][0] {
+		t.Error("hs: expected the buffer to be reused")
+	}
+}
+
+func TestParseHostsLineBytesErrorCopy(t *testing.T) {
+	line := []byte("x.x.x.x example.com")
+	_, _, err := ParseHostsLineBytes(line, nil)
+	if err == nil {
+		t.Fatal("err: expected non-nil error, got nil")
+	}
+
+	want := err.Error()
+	copy(line, "y.y.y.y")
+	if got := err.Error(); got != want {
+		t.Errorf("err.Error() changed with the input buffer: expected %q, got %q", want, got)
+	}
+}
+
+func TestHostsLoaderInterner(t *testing.T) {
+	in := NewInterner()
+	l := NewHostsLoader(strings.NewReader("0.0.0.0 a.example.com\n0.0.0.0 a.example.com\n"))
+	l.SetInterner(in)
+
+	HelpLoaderTest(t, l, true, Filter{Domain: "a.example.com", Line: 1}, false)
+	HelpLoaderTest(t, l, true, Filter{Domain: "a.example.com", Line: 2}, false)
+	HelpLoaderTest(t, l, false, Filter{}, false)
+
+	if in.Len() != 1 {
+		t.Errorf("in.Len(): expected 1, got %d", in.Len())
+	}
+}
+
+func HelpHostsLines(n int) []byte {
+	var b []byte
+	for i := range n {
+		b = append(b, "0.0.0.0 host"...)
+		b = strconv.AppendInt(b, int64(i%1000), 10)
+		b = append(b, ".example.com\n"...)
+	}
+	return b
+}
+
+func TestParseHostsLineBytesAllocs(t *testing.T) {
+	line := []byte("0.0.0.0 ads.example.com tracker.example.com # comment")
+	hs := make([][]byte, 0, 4)
+	got := testing.AllocsPerRun(100, func() {
+		_, hs, _ = ParseHostsLineBytes(line, hs)
+	})
+	if got != 0 {
+		t.Errorf("expected 0 allocs, got %v", got)
+	}
+}
+
+func BenchmarkParseHostsLine(b *testing.B) {
+	line := []byte("0.0.0.0 ads.example.com tracker.example.com")
+	b.ReportAllocs()
+	for range b.N {
+		ParseHostsLine(line)
+	}
+}
+
+func BenchmarkParseHostsLineBytes(b *testing.B) {
+	line := []byte("0.0.0.0 ads.example.com tracker.example.com")
+	var hs [][]byte
+	b.ReportAllocs()
+	for range b.N {
+		_, hs, _ = ParseHostsLineBytes(line, hs)
+	}
+}
+
+func BenchmarkHostsLoaderPerLine(b *testing.B) {
+	data := HelpHostsLines(b.N)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	l := NewHostsLoader(bytes.NewReader(data))
+	l.SetInterner(NewInterner())
+	for l.Load() {
+	}
+}
@@ -0,0 +1,320 @@
+package filter
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// IDNAProfile selects the IDNA rules used to convert names to ASCII.
+type IDNAProfile struct {
+	name       string
+	p          *idna.Profile
+	underscore bool
+	fast       bool
+}
+
+var (
+	IDNALookup       = &IDNAProfile{name: "lookup", p: idna.Lookup, fast: true}
+	IDNARegistration = &IDNAProfile{name: "registration", p: idna.Registration}
+	IDNAPunycode     = &IDNAProfile{name: "punycode", p: idna.Punycode}
+	IDNAUnderscore   = &IDNAProfile{name: "underscore", p: idna.Lookup, underscore: true, fast: true}
+)
+
+// NewIDNAProfile returns a profile built from the given idna options.
+func NewIDNAProfile(name string, opts ...idna.Option) *IDNAProfile {
+	return &IDNAProfile{name: name, p: idna.New(opts...)}
+}
+
+func ParseIDNAProfile(name string) (*IDNAProfile, error) {
+	for _, p := range []*IDNAProfile{IDNALookup, IDNARegistration, IDNAPunycode, IDNAUnderscore} {
+		if p.name == name {
+			return p, nil
+		}
+	}
+	return nil, errors.New("unknown IDNA profile " + strconv.Quote(name))
+}
+
+func (p *IDNAProfile) String() string {
+	if p == nil {
+		p = IDNALookup
+	}
+	return p.name
+}
+
+func (p *IDNAProfile) ToASCII(s string) (string, error) {
+	if p == nil {
+		p = IDNALookup
+	}
+
+	if p.fast {
+		if t, ok := asciiToASCII(s, p.underscore); ok {
+			return t, nil
+		}
+	}
+
+	in := s
+	var under []int
+	if p.underscore && strings.Contains(s, "_") {
+		for i, label := range splitLabels(s) {
+			if !strings.Contains(label, "_") {
+				continue
+			}
+			if !isASCII(label) {
+				return s, &IDNAError{
+					Domain: s,
+					Rule:   IDNARuleDisallowedRune,
+					Label:  label,
+					Err:    errors.New("underscore in a non-ASCII label"),
+				}
+			}
+			under = append(under, i)
+		}
+		in = strings.ReplaceAll(s, "_", "a")
+	}
+
+	t, err := p.p.ToASCII(in)
+	if err != nil {
+		return s, p.diagnose(s, err)
+	}
+	if len(under) > 0 {
+		labels := splitLabels(s)
+		out := strings.Split(t, ".")
+		if len(out) != len(labels) {
+			return s, &IDNAError{Domain: s, Rule: IDNARuleInvalidLabel, Err: errors.New("label count changed by mapping")}
+		}
+		for _, i := range under {
+			out[i] = strings.ToLower(labels[i])
+		}
+		t = strings.Join(out, ".")
+	}
+	return t, nil
+}
+
+func (p *IDNAProfile) ToUnicode(s string) (string, error) {
+	if p == nil {
+		p = IDNALookup
+	}
+
+	if !strings.Contains(strings.ToLower(s), "xn--") {
+		return s, nil
+	}
+
+	t, err := p.p.ToUnicode(s)
+	if err != nil {
+		return s, p.diagnose(s, err)
+	}
+	return t, nil
+}
+
+func IDNAToASCII(s string) (string, error) {
+	return IDNALookup.ToASCII(s)
+}
+
+func IDNAToUnicode(s string) (string, error) {
+	return IDNALookup.ToUnicode(s)
+}
+
+// DisplayMode selects how internationalized names are shown to people.
+type DisplayMode int
+
+const (
+	DisplayASCII DisplayMode = iota
+	DisplayUnicode
+	DisplayBoth
+)
+
+// Format shows domain in mode m. Non-graphic and bidi control runes in the
+// Unicode form are escaped so they cannot reorder or hide the output.
+func (m DisplayMode) Format(domain string) string {
+	if m == DisplayASCII {
+		return domain
+	}
+
+	u, err := IDNAToUnicode(domain)
+	if err != nil || u == domain {
+		return domain
+	}
+	u = escapeDisplay(u)
+	if m == DisplayUnicode {
+		return u
+	}
+	return u + " (" + domain + ")"
+}
+
+func escapeDisplay(s string) string {
+	var b []byte
+	for _, r := range s {
+		if unicode.IsGraphic(r) && !unicode.Is(unicode.Bidi_Control, r) {
+			b = utf8.AppendRune(b, r)
+			continue
+		}
+		q := strconv.QuoteRuneToASCII(r)
+		b = append(b, q[1:len(q)-1]...)
+	}
+	return string(b)
+}
+
+func asciiToASCII(s string, underscore bool) (string, bool) {
+	name := strings.TrimSuffix(s, ".")
+	if len(name) <= 0 || len(name) > 253 {
+		return "", false
+	}
+
+	upper := false
+	start := 0
+	for i := 0; i <= len(name); i++ {
+		if i < len(name) && name[i] != '.' {
+			c := name[i]
+			switch {
+			case 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-':
+			case c == '_' && underscore:
+			case 'A' <= c && c <= 'Z':
+				upper = true
+			default:
+				return "", false
+			}
+			continue
+		}
+
+		label := name[start:i]
+		if len(label) <= 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return "", false
+		}
+		if len(label) >= 4 && label[2] == '-' && label[3] == '-' {
+			return "", false
+		}
+		start = i + 1
+	}
+
+	if upper {
+		return strings.ToLower(s), true
+	}
+	return s, true
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// IDNARule names the IDNA rule a domain broke.
+type IDNARule string
+
+const (
+	IDNARuleEmptyLabel     IDNARule = "empty label"
+	IDNARuleLabelLength    IDNARule = "label longer than 63 bytes"
+	IDNARuleNameLength     IDNARule = "name longer than 253 bytes"
+	IDNARuleLeadingHyphen  IDNARule = "leading hyphen"
+	IDNARuleTrailingHyphen IDNARule = "trailing hyphen"
+	IDNARuleHyphen34       IDNARule = "hyphens in third and fourth positions"
+	IDNARuleDisallowedRune IDNARule = "disallowed rune"
+	IDNARulePunycode       IDNARule = "invalid punycode label"
+	IDNARuleInvalidLabel   IDNARule = "invalid label"
+)
+
+// diagnose converts each label of s on its own, so the reported rule, label
+// and error all come from the first label the profile rejects. err is kept
+// only when no single label fails and the name as a whole is at fault.
+func (p *IDNAProfile) diagnose(s string, err error) *IDNAError {
+	labels := splitLabels(strings.TrimSuffix(s, "."))
+	for _, label := range labels {
+		in := label
+		if p.underscore {
+			in = strings.ReplaceAll(label, "_", "a")
+		}
+		if _, lerr := p.p.ToASCII(in); lerr != nil {
+			return &IDNAError{Domain: s, Rule: labelRule(label, lerr), Label: label, Err: lerr}
+		}
+	}
+
+	rule := IDNARuleInvalidLabel
+	if a, _ := idna.Punycode.ToASCII(s); len(strings.TrimSuffix(a, ".")) > 253 {
+		rule = IDNARuleNameLength
+	} else if slices.Contains(labels, "") {
+		rule = IDNARuleEmptyLabel
+	}
+	return &IDNAError{Domain: s, Rule: rule, Err: err}
+}
+
+func labelRule(label string, err error) IDNARule {
+	if strings.HasPrefix(err.Error(), "idna: disallowed rune") {
+		return IDNARuleDisallowedRune
+	}
+
+	puny := false
+	if lower := strings.ToLower(label); strings.HasPrefix(lower, "xn--") {
+		u, err := idna.Punycode.ToUnicode(lower)
+		if err != nil || u == "" {
+			return IDNARulePunycode
+		}
+		label = u
+		puny = true
+	}
+	switch {
+	case label == "":
+		return IDNARuleEmptyLabel
+	case len(label) >= 4 && label[2:4] == "--":
+		return IDNARuleHyphen34
+	case label[0] == '-':
+		return IDNARuleLeadingHyphen
+	case label[len(label)-1] == '-':
+		return IDNARuleTrailingHyphen
+	}
+	if a, _ := idna.Punycode.ToASCII(label); len(a) > 63 {
+		return IDNARuleLabelLength
+	}
+	if puny {
+		return IDNARulePunycode
+	}
+	return IDNARuleInvalidLabel
+}
+
+func splitLabels(s string) []string {
+	var labels []string
+	start := 0
+	for i, r := range s {
+		if r == '.' || r == '。' || r == '．' || r == '｡' {
+			labels = append(labels, s[start:i])
+			start = i + utf8.RuneLen(r)
+		}
+	}
+	return append(labels, s[start:])
+}
+
+// IDNAError reports a domain that could not be converted to ASCII.
+type IDNAError struct {
+	Domain string
+	Rule   IDNARule
+	Label  string
+	Err    error
+}
+
+func (e *IDNAError) Error() string {
+	b := []byte("domain ")
+	b = strconv.AppendQuote(b, e.Domain)
+	b = append(b, ": "...)
+	if e.Rule != "" {
+		b = append(b, e.Rule...)
+		if e.Label != "" {
+			b = append(b, " in label "...)
+			b = strconv.AppendQuote(b, e.Label)
+		}
+		b = append(b, ": "...)
+	}
+	b = append(b, e.Err.Error()...)
+	return string(b)
+}
+
+func (e *IDNAError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,301 @@
+package filter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/idna"
+)
+
+func TestIDNAToASCIINormal(t *testing.T) {
+	gotS, gotErr := IDNAToASCII("お名前.com")
+
+	const wantS = "xn--t8jx73hngb.com"
+	if gotS != wantS {
+		t.Errorf("s: expected %q, got %q", wantS, gotS)
+	}
+
+	if gotErr != nil {
+		t.Errorf("err: expected nil, got %#v", gotErr)
+	}
+}
+
+func TestIDNAToASCIIError(t *testing.T) {
+	const inS = "--.com"
+	gotS, gotErr := IDNAToASCII(inS)
+
+	if gotS != inS {
+		t.Errorf("s: expected %q, got %q", inS, gotS)
+	}
+
+	if gotIDNAError, ok := gotErr.(*IDNAError); !ok {
+		t.Errorf("err.(type): expected *IDNAError, got %T", gotErr)
+	} else {
+		if gotIDNAError.Domain != inS {
+			t.Errorf("err.Domain: expected %q, got %q", inS, gotIDNAError.Domain)
+		}
+		if gotIDNAError.Err == nil {
+			t.Error("err.Err: expected non-nil error, got nil")
+		}
+	}
+}
+
+func TestIDNAErrorError(t *testing.T) {
+	err := &IDNAError{
+		Domain: "example.com",
+		Err:    errors.New("error message"),
+	}
+
+	const want = `domain "example.com": error message`
+	got := err.Error()
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestIDNAErrorErrorRule(t *testing.T) {
+	err := &IDNAError{
+		Domain: "-a.example.com",
+		Rule:   IDNARuleLeadingHyphen,
+		Label:  "-a",
+		Err:    errors.New("error message"),
+	}
+
+	const want = `domain "-a.example.com": leading hyphen in label "-a": error message`
+	got := err.Error()
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestIDNAProfileToASCII(t *testing.T) {
+	tt := []struct {
+		name      string
+		profile   *IDNAProfile
+		in        string
+		want      string
+		wantRule  IDNARule
+		wantLabel string
+	}{
+		{name: "LookupUnderscore", profile: IDNALookup, in: "_dmarc.example.com", want: "_dmarc.example.com", wantRule: IDNARuleDisallowedRune, wantLabel: "_dmarc"},
+		{name: "LookupLeadingHyphen", profile: IDNALookup, in: "-a.example.com", want: "-a.example.com", wantRule: IDNARuleLeadingHyphen, wantLabel: "-a"},
+		{name: "LookupTrailingHyphen", profile: IDNALookup, in: "a-.example.com", want: "a-.example.com", wantRule: IDNARuleTrailingHyphen, wantLabel: "a-"},
+		{name: "LookupHyphen34", profile: IDNALookup, in: "ab--c.com", want: "ab--c.com", wantRule: IDNARuleHyphen34, wantLabel: "ab--c"},
+		{name: "LookupPunycode", profile: IDNALookup, in: "xn--abc.com", want: "xn--abc.com", wantRule: IDNARulePunycode, wantLabel: "xn--abc"},
+		{name: "LookupNil", profile: nil, in: "お名前.com", want: "xn--t8jx73hngb.com"},
+		{name: "Underscore", profile: IDNAUnderscore, in: "_dmarc.Example.com", want: "_dmarc.example.com"},
+		{name: "UnderscoreSlowPath", profile: IDNAUnderscore, in: "_tcp.a..b", want: "_tcp.a..b"},
+		{name: "UnderscoreNonASCII", profile: IDNAUnderscore, in: "_x.お名前.com", want: "_x.xn--t8jx73hngb.com"},
+		{name: "UnderscoreInULabel", profile: IDNAUnderscore, in: "_x.お_名前.com", want: "_x.お_名前.com", wantRule: IDNARuleDisallowedRune, wantLabel: "お_名前"},
+		{name: "UnderscoreStillStrict", profile: IDNAUnderscore, in: "a*b.com", want: "a*b.com", wantRule: IDNARuleDisallowedRune, wantLabel: "a*b"},
+		{name: "RegistrationEmptyLabel", profile: IDNARegistration, in: "a..b", want: "a..b", wantRule: IDNARuleEmptyLabel},
+		{name: "RegistrationLabelLength", profile: IDNARegistration, in: strings.Repeat("a", 64) + ".com", want: strings.Repeat("a", 64) + ".com", wantRule: IDNARuleLabelLength, wantLabel: strings.Repeat("a", 64)},
+		{name: "Punycode", profile: IDNAPunycode, in: "a_b.com", want: "a_b.com"},
+		{name: "ManyValidPunycodeSpace", profile: IDNALookup, in: "xn--t8jx73hngb.a b.com", want: "xn--t8jx73hngb.a b.com", wantRule: IDNARuleDisallowedRune, wantLabel: "a b"},
+		{name: "ManyHyphenPunycodeBang", profile: IDNALookup, in: "a-b.xn--t8jx73hngb.c!d.com", want: "a-b.xn--t8jx73hngb.c!d.com", wantRule: IDNARuleDisallowedRune, wantLabel: "c!d"},
+		{name: "ManyTrailingHyphenFirst", profile: IDNALookup, in: "ok.a-.xn--abc.c!d", want: "ok.a-.xn--abc.c!d", wantRule: IDNARuleTrailingHyphen, wantLabel: "a-"},
+		{name: "ManyPunycodeFirst", profile: IDNALookup, in: "xn--abc.-a.c d", want: "xn--abc.-a.c d", wantRule: IDNARulePunycode, wantLabel: "xn--abc"},
+		{name: "ManyUnderscore", profile: IDNAUnderscore, in: "_a.ab--c.x y", want: "_a.ab--c.x y", wantRule: IDNARuleHyphen34, wantLabel: "ab--c"},
+		{name: "ManyRegistrationLength", profile: IDNARegistration, in: "ok." + strings.Repeat("a", 64) + ".-a", want: "ok." + strings.Repeat("a", 64) + ".-a", wantRule: IDNARuleLabelLength, wantLabel: strings.Repeat("a", 64)},
+	}
+
+	for _, tc := range tt {
+		got, err := tc.profile.ToASCII(tc.in)
+		if got != tc.want {
+			t.Errorf("%s: s: expected %q, got %q", tc.name, tc.want, got)
+		}
+
+		if tc.wantRule == "" {
+			if err != nil {
+				t.Errorf("%s: err: expected nil, got %#v", tc.name, err)
+			}
+			continue
+		}
+
+		var idnaErr *IDNAError
+		if !errors.As(err, &idnaErr) {
+			t.Errorf("%s: err: expected *IDNAError, got %#v", tc.name, err)
+			continue
+		}
+		if idnaErr.Rule != tc.wantRule {
+			t.Errorf("%s: err.Rule: expected %q, got %q", tc.name, tc.wantRule, idnaErr.Rule)
+		}
+		if idnaErr.Label != tc.wantLabel {
+			t.Errorf("%s: err.Label: expected %q, got %q", tc.name, tc.wantLabel, idnaErr.Label)
+		}
+	}
+}
+
+func TestParseIDNAProfile(t *testing.T) {
+	for _, want := range []*IDNAProfile{IDNALookup, IDNARegistration, IDNAPunycode, IDNAUnderscore} {
+		got, err := ParseIDNAProfile(want.String())
+		if got != want || err != nil {
+			t.Errorf("%q: expected (%v, nil), got (%v, %v)", want.String(), want, got, err)
+		}
+	}
+
+	if _, err := ParseIDNAProfile("strict"); err == nil {
+		t.Error("\"strict\": expected error, got nil")
+	}
+}
+
+func TestIDNAToASCIIFastPath(t *testing.T) {
+	tt := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{in: "example.com", want: "example.com", ok: true},
+		{in: "Ads.EXAMPLE.com", want: "ads.example.com", ok: true},
+		{in: "example.com.", want: "example.com.", ok: true},
+		{in: "a-b.example.com", want: "a-b.example.com", ok: true},
+		{in: "", ok: false},
+		{in: ".", ok: false},
+		{in: "a..b", ok: false},
+		{in: "-a.com", ok: false},
+		{in: "a-.com", ok: false},
+		{in: "xn--t8jx73hngb.com", ok: false},
+		{in: "ab--c.com", ok: false},
+		{in: "a_b.com", ok: false},
+		{in: "お名前.com", ok: false},
+		{in: strings.Repeat("a", 64) + ".com", ok: false},
+		{in: strings.Repeat("a.", 127) + "com", ok: false},
+	}
+
+	for _, tc := range tt {
+		got, ok := asciiToASCII(tc.in, false)
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("%q: expected (%q, %t), got (%q, %t)", tc.in, tc.want, tc.ok, got, ok)
+		}
+	}
+}
+
+func FuzzIDNAToASCII(f *testing.F) {
+	for _, s := range []string{"example.com", "EXAMPLE.com.", "xn--t8jx73hngb.com", "a--b.com", "-a.com", "a..b", "お名前.com", "a_b.com"} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		fast, ok := asciiToASCII(s, false)
+		slow, err := idna.Lookup.ToASCII(s)
+		if !ok {
+			return
+		}
+		if err != nil {
+			t.Fatalf("%q: fast path accepted a name the IDNA profile rejects: %v", s, err)
+		}
+		if fast != slow {
+			t.Fatalf("%q: fast path returned %q, IDNA profile returned %q", s, fast, slow)
+		}
+
+		got, err := IDNAToASCII(s)
+		if got != slow || err != nil {
+			t.Fatalf("%q: IDNAToASCII returned (%q, %v), expected (%q, nil)", s, got, err, slow)
+		}
+	})
+}
+
+func BenchmarkIDNAToASCII(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		IDNAToASCII("ads.tracker.example.com")
+	}
+}
+
+func BenchmarkIDNALookup(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		idna.Lookup.ToASCII("ads.tracker.example.com")
+	}
+}
+
+func TestIDNAToUnicode(t *testing.T) {
+	tt := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "example.com", want: "example.com"},
+		{in: "xn--t8jx73hngb.com", want: "お名前.com"},
+		{in: "XN--T8JX73HNGB.com", want: "お名前.com"},
+		{in: "xn--abc.com", want: "xn--abc.com", wantErr: true},
+	}
+
+	for _, tc := range tt {
+		got, err := IDNAToUnicode(tc.in)
+		if got != tc.want {
+			t.Errorf("%q: expected %q, got %q", tc.in, tc.want, got)
+		}
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q: err: expected error %t, got %v", tc.in, tc.wantErr, err)
+		}
+	}
+}
+
+func TestDisplayModeFormat(t *testing.T) {
+	tt := []struct {
+		mode DisplayMode
+		in   string
+		want string
+	}{
+		{mode: DisplayASCII, in: "xn--t8jx73hngb.com", want: "xn--t8jx73hngb.com"},
+		{mode: DisplayUnicode, in: "xn--t8jx73hngb.com", want: "お名前.com"},
+		{mode: DisplayBoth, in: "xn--t8jx73hngb.com", want: "お名前.com (xn--t8jx73hngb.com)"},
+		{mode: DisplayBoth, in: "example.com", want: "example.com"},
+		{mode: DisplayBoth, in: "xn--abc.com", want: "xn--abc.com"},
+	}
+
+	for _, tc := range tt {
+		got := tc.mode.Format(tc.in)
+		if got != tc.want {
+			t.Errorf("%d %q: expected %q, got %q", tc.mode, tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestEscapeDisplay(t *testing.T) {
+	tt := []struct {
+		in   string
+		want string
+	}{
+		{in: "お名前.com", want: "お名前.com"},
+		{in: "ex\u202eelpma.com", want: `ex\u202eelpma.com`},
+		{in: "a\u200fb", want: `a\u200fb`},
+		{in: "a\x01b", want: `a\x01b`},
+	}
+
+	for _, tc := range tt {
+		got := escapeDisplay(tc.in)
+		if got != tc.want {
+			t.Errorf("%q: expected %q, got %q", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestIDNAErrorErrMatchesLabel(t *testing.T) {
+	tt := []struct {
+		in        string
+		wantLabel string
+		wantErr   string
+	}{
+		{in: "xn--t8jx73hngb.a b.com", wantLabel: "a b", wantErr: "idna: disallowed rune U+0020"},
+		{in: "a-b.xn--t8jx73hngb.c!d.com", wantLabel: "c!d", wantErr: "idna: disallowed rune U+0021"},
+		{in: "xn--abc.a b", wantLabel: "xn--abc", wantErr: `idna: invalid label "\u0082\u0081\u0080"`},
+	}
+
+	for _, tc := range tt {
+		_, err := IDNAToASCII(tc.in)
+		var idnaErr *IDNAError
+		if !errors.As(err, &idnaErr) {
+			t.Errorf("%q: expected *IDNAError, got %#v", tc.in, err)
+			continue
+		}
+		if idnaErr.Label != tc.wantLabel {
+			t.Errorf("%q: Label: expected %q, got %q", tc.in, tc.wantLabel, idnaErr.Label)
+		}
+		if idnaErr.Err.Error() != tc.wantErr {
+			t.Errorf("%q: Err: expected %q, got %q", tc.in, tc.wantErr, idnaErr.Err.Error())
+		}
+	}
+}
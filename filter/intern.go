@@ -0,0 +1,33 @@
+package filter
+
+// Interner shares one string for each distinct name it has seen. It is not
+// safe for concurrent use. A nil *Interner copies every name without
+// interning it.
+type Interner struct {
+	m map[string]string
+}
+
+// NewInterner returns an empty Interner.
+func NewInterner() *Interner {
+	return &Interner{m: map[string]string{}}
+}
+
+func (in *Interner) Intern(b []byte) string {
+	if in == nil {
+		return string(b)
+	}
+
+	if s, ok := in.m[string(b)]; ok {
+		return s
+	}
+	s := string(b)
+	in.m[s] = s
+	return s
+}
+
+func (in *Interner) Len() int {
+	if in == nil {
+		return 0
+	}
+	return len(in.m)
+}
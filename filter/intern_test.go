@@ -0,0 +1,31 @@
+package filter
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestInternerIntern(t *testing.T) {
+	in := NewInterner()
+	a := in.Intern([]byte("example.com"))
+	b := in.Intern([]byte("example.com"))
+	if a != "example.com" {
+		t.Errorf("expected %q, got %q", "example.com", a)
+	}
+	if unsafe.StringData(a) != unsafe.StringData(b) {
+		t.Error("expected repeated names to share storage")
+	}
+	if in.Len() != 1 {
+		t.Errorf("in.Len(): expected 1, got %d", in.Len())
+	}
+}
+
+func TestInternerNil(t *testing.T) {
+	var in *Interner
+	if got := in.Intern([]byte("example.com")); got != "example.com" {
+		t.Errorf("expected %q, got %q", "example.com", got)
+	}
+	if in.Len() != 0 {
+		t.Errorf("in.Len(): expected 0, got %d", in.Len())
+	}
+}
@@ -0,0 +1,99 @@
+package filter
+
+import (
+	"io"
+	"iter"
+)
+
+func All(l Loader) iter.Seq2[Filter, error] {
+	return func(yield func(Filter, error) bool) {
+		for l.Load() {
+			if !yield(l.Filter(), l.Err()) {
+				return
+			}
+		}
+		if err := l.Err(); err != nil {
+			yield(Filter{}, err)
+		}
+	}
+}
+
+// Hosts and Simple close r when the iteration stops if r is an io.Closer.
+func Hosts(r io.Reader) iter.Seq2[Filter, error] {
+	return readerSeq(r, NewHostsLoader(r))
+}
+
+func Simple(r io.Reader) iter.Seq2[Filter, error] {
+	return readerSeq(r, NewSimpleLoader(r))
+}
+
+func readerSeq(r io.Reader, l Loader) iter.Seq2[Filter, error] {
+	return func(yield func(Filter, error) bool) {
+		if c, ok := r.(io.Closer); ok {
+			defer c.Close()
+		}
+		for f, err := range All(l) {
+			if !yield(f, err) {
+				return
+			}
+		}
+	}
+}
+
+func Concat(seqs ...iter.Seq2[Filter, error]) iter.Seq2[Filter, error] {
+	return func(yield func(Filter, error) bool) {
+		for _, seq := range seqs {
+			for f, err := range seq {
+				if !yield(f, err) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func Keep(seq iter.Seq2[Filter, error], keep func(Filter) bool) iter.Seq2[Filter, error] {
+	return func(yield func(Filter, error) bool) {
+		for f, err := range seq {
+			if err == nil && !keep(f) {
+				continue
+			}
+			if !yield(f, err) {
+				return
+			}
+		}
+	}
+}
+
+func Map(seq iter.Seq2[Filter, error], fn func(Filter) Filter) iter.Seq2[Filter, error] {
+	return func(yield func(Filter, error) bool) {
+		for f, err := range seq {
+			if !yield(fn(f), err) {
+				return
+			}
+		}
+	}
+}
+
+func Dedupe(seq iter.Seq2[Filter, error]) iter.Seq2[Filter, error] {
+	return func(yield func(Filter, error) bool) {
+		seen := map[Filter]struct{}{}
+		for f, err := range seq {
+			if err == nil {
+				key := Filter{
+					Exception: f.Exception,
+					Kind:      f.Kind,
+					Domain:    f.Domain,
+					Pattern:   f.Pattern,
+				}
+				if _, ok := seen[key]; ok {
+					continue
+				}
+				seen[key] = struct{}{}
+			}
+			if !yield(f, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package filter
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type iterItem struct {
+	F     Filter
+	IsErr bool
+}
+
+func HelpCollect(seq iter.Seq2[Filter, error]) []iterItem {
+	var items []iterItem
+	for f, err := range seq {
+		items = append(items, iterItem{F: f, IsErr: err != nil})
+	}
+	return items
+}
+
+func TestAll(t *testing.T) {
+	l := NewSimpleLoader(strings.NewReader("1.example.com\n--.com\n"))
+	got := HelpCollect(All(l))
+	want := []iterItem{
+		{F: Filter{Domain: "1.example.com", Line: 1}},
+		{F: Filter{Domain: "--.com", Line: 2}, IsErr: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestAllReadError(t *testing.T) {
+	mockErr := errors.New("test")
+	l := NewSimpleLoader(&ErrorReader{Err: mockErr})
+
+	var gotErrs []error
+	for _, err := range All(l) {
+		gotErrs = append(gotErrs, err)
+	}
+	if len(gotErrs) != 1 || gotErrs[0] != mockErr {
+		t.Errorf("expected [%v], got %v", mockErr, gotErrs)
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	Closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.Closed = true
+	return nil
+}
+
+func TestHostsBreakCloses(t *testing.T) {
+	r := &closeRecorder{Reader: strings.NewReader("127.0.0.1 1.example.com 2.example.com\n")}
+	for f := range Hosts(r) {
+		if f.Domain != "1.example.com" {
+			t.Errorf("f.Domain: expected %q, got %q", "1.example.com", f.Domain)
+		}
+		break
+	}
+	if !r.Closed {
+		t.Error("reader was not closed after break")
+	}
+}
+
+func TestConcatKeepMapDedupe(t *testing.T) {
+	seq := Concat(
+		Hosts(strings.NewReader("0.0.0.0 ads.example.com\n0.0.0.0 skip.example.com\n")),
+		Simple(strings.NewReader("ads.example.com\n--.com\ntracker.example.com\n")),
+	)
+	seq = Keep(seq, func(f Filter) bool { return !strings.HasPrefix(f.Domain, "skip.") })
+	seq = Map(seq, func(f Filter) Filter {
+		f.Name = "merged"
+		return f
+	})
+	seq = Dedupe(seq)
+
+	got := HelpCollect(seq)
+	want := []iterItem{
+		{F: Filter{Domain: "ads.example.com", Name: "merged", Line: 1}},
+		{F: Filter{Domain: "--.com", Name: "merged", Line: 2}, IsErr: true},
+		{F: Filter{Domain: "tracker.example.com", Name: "merged", Line: 3}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,116 @@
+// Package filter reads domain blocklists and allowlists in hosts, simple and
+// Pi-hole regex formats, and normalizes every entry into a Filter.
+package filter
+
+import (
+	"strconv"
+)
+
+// Loader reads filters one at a time, like bufio.Scanner.
+type Loader interface {
+	Load() bool
+	Filter() Filter
+	Err() error
+}
+
+// Filter is one normalized list entry.
+type Filter struct {
+	Exception bool
+	Kind      Kind
+	Domain    string
+	Pattern   string
+	Name      string
+	Line      int
+}
+
+func (f Filter) Source() string {
+	b := []byte(f.Name)
+	if f.Line > 0 {
+		if len(b) > 0 {
+			b = append(b, ':')
+		}
+		b = strconv.AppendInt(b, int64(f.Line), 10)
+	}
+	return string(b)
+}
+
+// Kind tells how a Filter matches names.
+type Kind int
+
+const (
+	KindDomain Kind = iota
+	KindRegex
+	KindGlob
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindDomain:
+		return "domain"
+	case KindRegex:
+		return "regex"
+	case KindGlob:
+		return "glob"
+	default:
+		return "Kind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// ResourceError locates an error in a list by name, line and column.
+type ResourceError struct {
+	Name   string
+	Line   int
+	Column int
+	Text   string
+	Err    error
+}
+
+func (e *ResourceError) Error() string {
+	b := []byte(e.Name)
+
+	if e.Line > 0 {
+		if len(b) > 0 {
+			b = append(b, ':')
+		}
+		b = strconv.AppendInt(b, int64(e.Line), 10)
+
+		if e.Column > 0 {
+			b = append(b, ':')
+			b = strconv.AppendInt(b, int64(e.Column), 10)
+		}
+	}
+
+	w := e.Err.Error()
+	if len(w) > 0 {
+		if len(b) > 0 {
+			b = append(b, ": "...)
+		}
+		b = append(b, w...)
+	}
+
+	return string(b)
+}
+
+func (e *ResourceError) Detail() string {
+	if len(e.Text) <= 0 {
+		return ""
+	}
+
+	b := []byte(e.Text)
+	if e.Column > 0 {
+		b = append(b, '\n')
+		for _, r := range e.Text[:min(e.Column-1, len(e.Text))] {
+			if r == '\t' {
+				b = append(b, '\t')
+			} else {
+				b = append(b, ' ')
+			}
+		}
+		b = append(b, '^')
+	}
+	return string(b)
+}
+
+func (e *ResourceError) Unwrap() error {
+	return e.Err
+}
@@ -1,4 +1,4 @@
-package main
+package filter
 
 import (
 	"errors"
@@ -56,6 +56,25 @@ func TestResourceErrorError(t *testing.T) {
 			},
 			want: "hosts.txt:52149: some error",
 		},
+		{
+			name: "Column",
+			in: &ResourceError{
+				Name:   "hosts.txt",
+				Line:   12,
+				Column: 9,
+				Err:    errors.New("some error"),
+			},
+			want: "hosts.txt:12:9: some error",
+		},
+		{
+			name: "ColumnWithoutLine",
+			in: &ResourceError{
+				Name:   "hosts.txt",
+				Column: 9,
+				Err:    errors.New("some error"),
+			},
+			want: "hosts.txt: some error",
+		},
 	}
 
 	for _, tc := range tt {
@@ -66,6 +85,66 @@ func TestResourceErrorError(t *testing.T) {
 	}
 }
 
+func TestResourceErrorDetail(t *testing.T) {
+	tt := []struct {
+		name string
+		in   *ResourceError
+		want string
+	}{
+		{
+			name: "Empty",
+			in:   &ResourceError{Column: 3},
+			want: "",
+		},
+		{
+			name: "NoColumn",
+			in:   &ResourceError{Text: "0.0.0.0 example.com"},
+			want: "0.0.0.0 example.com",
+		},
+		{
+			name: "Column",
+			in:   &ResourceError{Text: "0.0.0.0 --.com", Column: 9},
+			want: "0.0.0.0 --.com\n        ^",
+		},
+		{
+			name: "Tab",
+			in:   &ResourceError{Text: "0.0.0.0\t--.com", Column: 9},
+			want: "0.0.0.0\t--.com\n       \t^",
+		},
+		{
+			name: "Unicode",
+			in:   &ResourceError{Text: "お名前.com --.com", Column: 15},
+			want: "お名前.com --.com\n        ^",
+		},
+	}
+
+	for _, tc := range tt {
+		got := tc.in.Detail()
+		if got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestFilterSource(t *testing.T) {
+	tt := []struct {
+		in   Filter
+		want string
+	}{
+		{in: Filter{}, want: ""},
+		{in: Filter{Name: "hosts.txt"}, want: "hosts.txt"},
+		{in: Filter{Line: 12}, want: "12"},
+		{in: Filter{Name: "hosts.txt", Line: 12}, want: "hosts.txt:12"},
+	}
+
+	for _, tc := range tt {
+		got := tc.in.Source()
+		if got != tc.want {
+			t.Errorf("%#v: expected %q, got %q", tc.in, tc.want, got)
+		}
+	}
+}
+
 func HelpLoaderTest(t *testing.T, l Loader, wantOK bool, wantF Filter, wantHasErr bool) {
 	t.Helper()
 
@@ -0,0 +1,164 @@
+package filter
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func CompilePattern(kind Kind, pattern string) (*regexp.Regexp, error) {
+	var expr string
+	switch kind {
+	case KindRegex:
+		expr = pattern
+	case KindGlob:
+		expr = GlobToRegex(pattern)
+	default:
+		return nil, &PatternError{
+			Pattern: pattern,
+			Err:     errors.New("not a pattern kind: " + kind.String()),
+		}
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, &PatternError{
+			Pattern: pattern,
+			Err:     err,
+		}
+	}
+	return re, nil
+}
+
+func GlobToRegex(glob string) string {
+	var b strings.Builder
+	b.WriteByte('^')
+	for len(glob) > 0 {
+		i := strings.IndexAny(glob, "*?")
+		if i < 0 {
+			b.WriteString(regexp.QuoteMeta(glob))
+			break
+		}
+
+		b.WriteString(regexp.QuoteMeta(glob[:i]))
+		if glob[i] == '*' {
+			b.WriteString(".*")
+		} else {
+			b.WriteByte('.')
+		}
+		glob = glob[i+1:]
+	}
+	b.WriteByte('$')
+	return b.String()
+}
+
+func ExpandPattern(f Filter, corpus []string) ([]Filter, error) {
+	if f.Kind == KindDomain {
+		return []Filter{f}, nil
+	}
+
+	re, err := CompilePattern(f.Kind, f.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var fs []Filter
+	for _, domain := range corpus {
+		if re.MatchString(domain) {
+			e := f
+			e.Kind = KindDomain
+			e.Domain = domain
+			e.Pattern = ""
+			fs = append(fs, e)
+		}
+	}
+	return fs, nil
+}
+
+// Matcher decides whether a name is blocked by a set of filters.
+type Matcher struct {
+	deny    map[string]struct{}
+	allow   map[string]struct{}
+	denyRe  []*regexp.Regexp
+	allowRe []*regexp.Regexp
+}
+
+// NewMatcher returns an empty Matcher.
+func NewMatcher() *Matcher {
+	return &Matcher{
+		deny:  map[string]struct{}{},
+		allow: map[string]struct{}{},
+	}
+}
+
+func (m *Matcher) Add(f Filter) error {
+	if f.Kind == KindDomain {
+		if f.Exception {
+			m.allow[f.Domain] = struct{}{}
+		} else {
+			m.deny[f.Domain] = struct{}{}
+		}
+		return nil
+	}
+
+	re, err := CompilePattern(f.Kind, f.Pattern)
+	if err != nil {
+		return err
+	}
+	if f.Exception {
+		m.allowRe = append(m.allowRe, re)
+	} else {
+		m.denyRe = append(m.denyRe, re)
+	}
+	return nil
+}
+
+func (m *Matcher) Match(domain string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if matchDomain(m.allow, domain) || matchRegex(m.allowRe, domain) {
+		return false
+	}
+	return matchDomain(m.deny, domain) || matchRegex(m.denyRe, domain)
+}
+
+func matchDomain(set map[string]struct{}, domain string) bool {
+	for {
+		if _, ok := set[domain]; ok {
+			return true
+		}
+
+		i := strings.IndexByte(domain, '.')
+		if i < 0 {
+			return false
+		}
+		domain = domain[i+1:]
+	}
+}
+
+func matchRegex(res []*regexp.Regexp, domain string) bool {
+	for _, re := range res {
+		if re.MatchString(domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// PatternError reports a regex or glob filter that does not compile.
+type PatternError struct {
+	Pattern string
+	Err     error
+}
+
+func (e *PatternError) Error() string {
+	b := []byte("pattern ")
+	b = strconv.AppendQuote(b, e.Pattern)
+	b = append(b, ": "...)
+	b = append(b, e.Err.Error()...)
+	return string(b)
+}
+
+func (e *PatternError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,114 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestGlobToRegex(t *testing.T) {
+	tt := []struct {
+		in   string
+		want string
+	}{
+		{in: "", want: "^$"},
+		{in: "example.com", want: `^example\.com$`},
+		{in: "*.example.com", want: `^.*\.example\.com$`},
+		{in: "ad?.example.com", want: `^ad.\.example\.com$`},
+	}
+
+	for _, tc := range tt {
+		got := GlobToRegex(tc.in)
+		if got != tc.want {
+			t.Errorf("%q: expected %q, got %q", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestCompilePatternError(t *testing.T) {
+	_, err := CompilePattern(KindRegex, "(")
+
+	var patErr *PatternError
+	if !errors.As(err, &patErr) {
+		t.Fatalf("err: expected *PatternError, got %#v", err)
+	}
+	if patErr.Pattern != "(" {
+		t.Errorf("err.Pattern: expected %q, got %q", "(", patErr.Pattern)
+	}
+}
+
+func TestExpandPattern(t *testing.T) {
+	corpus := []string{"ad1.example.com", "www.example.com", "ad.example.org"}
+
+	f := Filter{Exception: true, Kind: KindRegex, Pattern: `^ad[0-9]*\.`, Name: "regex.list", Line: 3}
+	got, err := ExpandPattern(f, corpus)
+	if err != nil {
+		t.Fatalf("err: expected nil, got %#v", err)
+	}
+
+	want := []Filter{
+		{Exception: true, Domain: "ad1.example.com", Name: "regex.list", Line: 3},
+		{Exception: true, Domain: "ad.example.org", Name: "regex.list", Line: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestExpandPatternDomain(t *testing.T) {
+	f := Filter{Exception: true, Domain: "example.com"}
+	got, err := ExpandPattern(f, nil)
+	if err != nil {
+		t.Fatalf("err: expected nil, got %#v", err)
+	}
+	if !reflect.DeepEqual(got, []Filter{f}) {
+		t.Errorf("expected %v, got %v", []Filter{f}, got)
+	}
+}
+
+func TestMatcherMatch(t *testing.T) {
+	m := NewMatcher()
+	fs := []Filter{
+		{Domain: "example.com"},
+		{Exception: true, Domain: "good.example.com"},
+		{Kind: KindRegex, Pattern: `^ad[0-9]*\.`},
+		{Kind: KindGlob, Pattern: "*.tracker.example"},
+		{Exception: true, Kind: KindGlob, Pattern: "ad0.*"},
+	}
+	for _, f := range fs {
+		if err := m.Add(f); err != nil {
+			t.Fatalf("m.Add(%v): %v", f, err)
+		}
+	}
+
+	tt := []struct {
+		in   string
+		want bool
+	}{
+		{in: "example.com", want: true},
+		{in: "www.example.com", want: true},
+		{in: "WWW.Example.COM.", want: true},
+		{in: "good.example.com", want: false},
+		{in: "sub.good.example.com", want: false},
+		{in: "notexample.com", want: false},
+		{in: "ad12.example.org", want: true},
+		{in: "ad0.example.org", want: false},
+		{in: "x.tracker.example", want: true},
+		{in: "tracker.example", want: false},
+	}
+
+	for _, tc := range tt {
+		got := m.Match(tc.in)
+		if got != tc.want {
+			t.Errorf("%q: expected %t, got %t", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestMatcherAddError(t *testing.T) {
+	m := NewMatcher()
+	err := m.Add(Filter{Kind: KindRegex, Pattern: "["})
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+}
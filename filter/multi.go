@@ -0,0 +1,149 @@
+package filter
+
+import (
+	"slices"
+	"sync"
+)
+
+// Source is a named Loader read by a MultiLoader.
+type Source struct {
+	Name   string
+	Loader Loader
+}
+
+// SourceStats counts the filters and errors read from one Source.
+type SourceStats struct {
+	Name    string
+	Filters int
+	Errors  int
+	Err     error
+}
+
+// MultiLoader reads several sources one after another. In concurrent mode
+// each source is read by its own goroutine; the goroutines exit once Load
+// returns false, so a caller that stops earlier must call Close.
+type MultiLoader struct {
+	srcs  []Source
+	stats []SourceStats
+	conc  bool
+
+	i     int
+	chs   []chan multiItem
+	done  chan struct{}
+	close sync.Once
+
+	f   Filter
+	err error
+}
+
+type multiItem struct {
+	f    Filter
+	err  error
+	last bool
+}
+
+// NewMultiLoader returns a MultiLoader that reads srcs in order.
+func NewMultiLoader(srcs ...Source) *MultiLoader {
+	stats := make([]SourceStats, len(srcs))
+	for i, src := range srcs {
+		stats[i].Name = src.Name
+	}
+	return &MultiLoader{
+		srcs:  srcs,
+		stats: stats,
+		done:  make(chan struct{}),
+	}
+}
+
+func (l *MultiLoader) SetConcurrent(conc bool) {
+	l.conc = conc
+}
+
+func (l *MultiLoader) Load() bool {
+	if l.conc && l.chs == nil {
+		l.start()
+	}
+
+	for l.i < len(l.srcs) {
+		var item multiItem
+		if l.chs != nil {
+			item = <-l.chs[l.i]
+		} else {
+			item = l.next(l.srcs[l.i].Loader)
+		}
+
+		if item.last && item.err == nil {
+			l.i++
+			continue
+		}
+
+		src := l.srcs[l.i]
+		st := &l.stats[l.i]
+		f, err := item.f, item.err
+		if err != nil {
+			st.Errors++
+			if resErr, ok := err.(*ResourceError); ok {
+				resErr.Name = src.Name
+			} else {
+				err = &ResourceError{Name: src.Name, Err: err}
+			}
+		} else {
+			st.Filters++
+		}
+		if item.last {
+			st.Err = err
+			l.i++
+		} else if f != (Filter{}) {
+			f.Name = src.Name
+		}
+
+		l.f = f
+		l.err = err
+		return true
+	}
+
+	l.f = Filter{}
+	l.err = nil
+	return false
+}
+
+func (l *MultiLoader) next(sl Loader) multiItem {
+	if sl.Load() {
+		return multiItem{f: sl.Filter(), err: sl.Err()}
+	}
+	return multiItem{err: sl.Err(), last: true}
+}
+
+func (l *MultiLoader) start() {
+	l.chs = make([]chan multiItem, len(l.srcs))
+	for i, src := range l.srcs {
+		ch := make(chan multiItem, 256)
+		l.chs[i] = ch
+		go func() {
+			for {
+				item := l.next(src.Loader)
+				select {
+				case ch <- item:
+				case <-l.done:
+					return
+				}
+				if item.last {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// Close stops the goroutines started in concurrent mode. It does not close
+// the sources.
+func (l *MultiLoader) Close() {
+	l.close.Do(func() { close(l.done) })
+}
+
+func (l *MultiLoader) Stats() []SourceStats {
+	return slices.Clone(l.stats)
+}
+
+func (l *MultiLoader) Filter() Filter { return l.f }
+func (l *MultiLoader) Err() error     { return l.err }
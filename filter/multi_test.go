@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func HelpMultiSources() []Source {
+	return []Source{
+		{Name: "hosts.txt", Loader: NewHostsLoader(strings.NewReader("0.0.0.0 1.example.com\n192.168.0.1 2.example.com\n"))},
+		{Name: "broken.txt", Loader: NewSimpleLoader(&ErrorReader{Err: errors.New("test")})},
+		{Name: "simple.txt", Loader: NewSimpleLoader(strings.NewReader("3.example.com\n--.com\n"))},
+	}
+}
+
+func TestMultiLoaderLoad(t *testing.T) {
+	for _, conc := range []bool{false, true} {
+		l := NewMultiLoader(HelpMultiSources()...)
+		l.SetConcurrent(conc)
+		defer l.Close()
+
+		HelpLoaderTest(t, l, true, Filter{Domain: "1.example.com", Name: "hosts.txt", Line: 1}, false)
+		HelpLoaderTest(t, l, true, Filter{}, true)
+		HelpResourceErrorTest(t, "l.Err()", l.Err(), "hosts.txt", 2)
+		HelpLoaderTest(t, l, true, Filter{}, true)
+		HelpResourceErrorTest(t, "l.Err()", l.Err(), "broken.txt", 0)
+		HelpLoaderTest(t, l, true, Filter{Domain: "3.example.com", Name: "simple.txt", Line: 1}, false)
+		HelpLoaderTest(t, l, true, Filter{Domain: "--.com", Name: "simple.txt", Line: 2}, true)
+		HelpResourceErrorTest(t, "l.Err()", l.Err(), "simple.txt", 2)
+		HelpLoaderTest(t, l, false, Filter{}, false)
+
+		gotStats := l.Stats()
+		wantStats := []SourceStats{
+			{Name: "hosts.txt", Filters: 1, Errors: 1},
+			{Name: "broken.txt", Filters: 0, Errors: 1},
+			{Name: "simple.txt", Filters: 1, Errors: 1},
+		}
+		if len(gotStats) != len(wantStats) {
+			t.Fatalf("concurrent=%t: len(l.Stats()): expected %d, got %d", conc, len(wantStats), len(gotStats))
+		}
+		for i, want := range wantStats {
+			got := gotStats[i]
+			if got.Name != want.Name || got.Filters != want.Filters || got.Errors != want.Errors {
+				t.Errorf("concurrent=%t: l.Stats()[%d]: expected %v, got %v", conc, i, want, got)
+			}
+		}
+	}
+}
+
+func TestMultiLoaderStatsErr(t *testing.T) {
+	l := NewMultiLoader(HelpMultiSources()...)
+	for l.Load() {
+	}
+
+	stats := l.Stats()
+	if stats[0].Err != nil {
+		t.Errorf("stats[0].Err: expected nil, got %#v", stats[0].Err)
+	}
+	HelpResourceErrorTest(t, "stats[1].Err", stats[1].Err, "broken.txt", 0)
+	if stats[2].Err != nil {
+		t.Errorf("stats[2].Err: expected nil, got %#v", stats[2].Err)
+	}
+}
+
+func TestMultiLoaderStatsCopy(t *testing.T) {
+	l := NewMultiLoader(HelpMultiSources()...)
+	for l.Load() {
+	}
+
+	stats := l.Stats()
+	stats[0].Filters = 100
+	stats[0].Name = "changed"
+
+	got := l.Stats()[0]
+	want := SourceStats{Name: "hosts.txt", Filters: 1, Errors: 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("l.Stats()[0]: expected %v, got %v", want, got)
+	}
+}
+
+func TestMultiLoaderEmpty(t *testing.T) {
+	l := NewMultiLoader()
+	HelpLoaderTest(t, l, false, Filter{}, false)
+}
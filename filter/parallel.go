@@ -0,0 +1,206 @@
+package filter
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+const defaultChunkSize = 256 * 1024
+
+// ParallelLoader parses chunks of one list on several goroutines and
+// returns the filters in input order.
+type ParallelLoader struct {
+	r         io.Reader
+	newLoader func(io.Reader) Loader
+	workers   int
+	chunkSize int
+
+	order chan chan parallelResult
+	done  chan struct{}
+	close sync.Once
+
+	items   []multiItem
+	eof     bool
+	lastErr error
+
+	f   Filter
+	err error
+}
+
+type parallelJob struct {
+	data   []byte
+	offset int
+	res    chan parallelResult
+}
+
+type parallelResult struct {
+	items []multiItem
+	err   error
+}
+
+// NewParallelLoader returns a ParallelLoader that parses r with loaders made
+// by newLoader.
+func NewParallelLoader(r io.Reader, newLoader func(io.Reader) Loader) *ParallelLoader {
+	return &ParallelLoader{
+		r:         r,
+		newLoader: newLoader,
+		workers:   runtime.GOMAXPROCS(0),
+		chunkSize: defaultChunkSize,
+		done:      make(chan struct{}),
+	}
+}
+
+func (l *ParallelLoader) SetWorkers(n int) {
+	l.workers = max(n, 1)
+}
+
+func (l *ParallelLoader) SetChunkSize(n int) {
+	l.chunkSize = max(n, 1)
+}
+
+func (l *ParallelLoader) Load() bool {
+	if l.order == nil {
+		l.start()
+	}
+
+	for len(l.items) <= 0 {
+		if l.eof {
+			l.f = Filter{}
+			l.err = l.lastErr
+			return false
+		}
+
+		res, ok := <-l.order
+		if !ok {
+			l.eof = true
+			continue
+		}
+
+		r := <-res
+		l.items = r.items
+		if r.err != nil {
+			l.eof = true
+			l.lastErr = r.err
+			l.Close()
+		}
+	}
+
+	item := l.items[0]
+	l.items = l.items[1:]
+	l.f = item.f
+	l.err = item.err
+	return true
+}
+
+func (l *ParallelLoader) start() {
+	l.order = make(chan chan parallelResult, l.workers*2)
+	jobs := make(chan parallelJob)
+
+	var wg sync.WaitGroup
+	for range l.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job.res <- l.run(job)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(l.order)
+		defer wg.Wait()
+		defer close(jobs)
+		l.split(jobs)
+	}()
+}
+
+func (l *ParallelLoader) split(jobs chan<- parallelJob) {
+	br := bufio.NewReaderSize(l.r, l.chunkSize)
+	offset := 0
+	var buf []byte
+	for {
+		prev := len(buf)
+		buf = slices.Grow(buf, l.chunkSize)
+		n, err := io.ReadFull(br, buf[prev:prev+l.chunkSize])
+		buf = buf[:prev+n]
+
+		var data []byte
+		atEnd := err != nil
+		if i := bytes.LastIndexByte(buf[prev:], '\n'); i >= 0 && !atEnd {
+			data = buf[:prev+i+1]
+			buf = append([]byte(nil), buf[prev+i+1:]...)
+		} else if atEnd || len(buf) > bufio.MaxScanTokenSize {
+			data, buf = buf, nil
+		} else {
+			continue
+		}
+
+		if len(data) > 0 {
+			if !l.submit(jobs, parallelJob{data: data, offset: offset}) {
+				return
+			}
+			offset += bytes.Count(data, []byte{'\n'})
+		}
+
+		if atEnd {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = nil
+			}
+			if err != nil {
+				res := make(chan parallelResult, 1)
+				res <- parallelResult{err: err}
+				select {
+				case l.order <- res:
+				case <-l.done:
+				}
+			}
+			return
+		}
+	}
+}
+
+func (l *ParallelLoader) submit(jobs chan<- parallelJob, job parallelJob) bool {
+	job.res = make(chan parallelResult, 1)
+	select {
+	case l.order <- job.res:
+	case <-l.done:
+		return false
+	}
+	select {
+	case jobs <- job:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+func (l *ParallelLoader) run(job parallelJob) parallelResult {
+	res := parallelResult{
+		items: make([]multiItem, 0, bytes.Count(job.data, []byte{'\n'})+1),
+	}
+	sl := l.newLoader(bytes.NewReader(job.data))
+	for sl.Load() {
+		f, err := sl.Filter(), sl.Err()
+		if f.Line > 0 {
+			f.Line += job.offset
+		}
+		if resErr, ok := err.(*ResourceError); ok && resErr.Line > 0 {
+			resErr.Line += job.offset
+		}
+		res.items = append(res.items, multiItem{f: f, err: err})
+	}
+	res.err = sl.Err()
+	return res
+}
+
+func (l *ParallelLoader) Close() {
+	l.close.Do(func() { close(l.done) })
+}
+
+func (l *ParallelLoader) Filter() Filter { return l.f }
+func (l *ParallelLoader) Err() error     { return l.err }
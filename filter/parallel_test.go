@@ -0,0 +1,158 @@
+package filter
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type loadResult struct {
+	F   Filter
+	Err string
+}
+
+func HelpDrain(l Loader) []loadResult {
+	var rs []loadResult
+	for {
+		ok := l.Load()
+		r := loadResult{F: l.Filter()}
+		if err := l.Err(); err != nil {
+			r.Err = err.Error()
+		}
+		rs = append(rs, r)
+		if !ok {
+			return rs
+		}
+	}
+}
+
+func HelpParallelFixture() string {
+	var sb strings.Builder
+	for i := range 500 {
+		n := strconv.Itoa(i)
+		switch i % 10 {
+		case 0:
+			sb.WriteString("# comment " + n + "\n")
+		case 1:
+			sb.WriteString("0.0.0.0 a" + n + ".example.com b" + n + ".example.com\r\n")
+		case 2:
+			sb.WriteString("192.168.0.1 c" + n + ".example.com\n")
+		case 3:
+			sb.WriteString("127.0.0.1 お名前" + n + ".com\n")
+		case 4:
+			sb.WriteString("0.0.0.0 --" + n + ".com\n")
+		case 5:
+			sb.WriteString("\n")
+		default:
+			sb.WriteString("0.0.0.0 d" + n + ".example.com # trailing\n")
+		}
+	}
+	sb.WriteString("0.0.0.0 last.example.com")
+	return sb.String()
+}
+
+func TestParallelLoaderEquivalence(t *testing.T) {
+	fixtures := map[string]string{
+		"Normal":  HelpParallelFixture(),
+		"TooLong": "0.0.0.0 a.example.com\n" + strings.Repeat("x", 70000) + "\n0.0.0.0 b.example.com\n",
+		"Empty":   "",
+	}
+	factories := map[string]func(io.Reader) Loader{
+		"Hosts": func(r io.Reader) Loader {
+			l := NewHostsLoader(r)
+			l.SetName("hosts.txt")
+			return l
+		},
+		"Simple": func(r io.Reader) Loader {
+			l := NewSimpleLoader(r)
+			l.SetName("simple.txt")
+			return l
+		},
+	}
+
+	for fname, fixture := range fixtures {
+		for lname, newLoader := range factories {
+			want := HelpDrain(newLoader(strings.NewReader(fixture)))
+			for _, chunkSize := range []int{1, 7, 64, 4096, defaultChunkSize} {
+				for _, workers := range []int{1, 4} {
+					l := NewParallelLoader(strings.NewReader(fixture), newLoader)
+					l.SetChunkSize(chunkSize)
+					l.SetWorkers(workers)
+					got := HelpDrain(l)
+					l.Close()
+
+					if !reflect.DeepEqual(got, want) {
+						t.Errorf("%s/%s chunk=%d workers=%d: output differs from sequential loader", fname, lname, chunkSize, workers)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestParallelLoaderReadError(t *testing.T) {
+	mockErr := errors.New("test")
+	newLoader := func(r io.Reader) Loader { return NewSimpleLoader(r) }
+	newReader := func() io.Reader {
+		return io.MultiReader(strings.NewReader("1.example.com\n2.exa"), &ErrorReader{Err: mockErr})
+	}
+
+	want := HelpDrain(newLoader(newReader()))
+	l := NewParallelLoader(newReader(), newLoader)
+	l.SetChunkSize(4)
+	got := HelpDrain(l)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if l.Err() != mockErr {
+		t.Errorf("l.Err(): expected %#v, got %#v", mockErr, l.Err())
+	}
+}
+
+func TestParallelLoaderClose(t *testing.T) {
+	newLoader := func(r io.Reader) Loader { return NewSimpleLoader(r) }
+	l := NewParallelLoader(strings.NewReader(strings.Repeat("example.com\n", 10000)), newLoader)
+	l.SetChunkSize(16)
+	if !l.Load() {
+		t.Fatal("first load failed")
+	}
+	l.Close()
+}
+
+var benchFixture = sync.OnceValue(func() []byte {
+	var b []byte
+	for i := range 2_000_000 {
+		b = append(b, "0.0.0.0 host"...)
+		b = strconv.AppendInt(b, int64(i), 10)
+		b = append(b, ".example.com\n"...)
+	}
+	return b
+})
+
+func BenchmarkHostsSequential(b *testing.B) {
+	fixture := benchFixture()
+	b.SetBytes(int64(len(fixture)))
+	b.ResetTimer()
+	for range b.N {
+		l := NewHostsLoader(strings.NewReader(string(fixture)))
+		for l.Load() {
+		}
+	}
+}
+
+func BenchmarkHostsParallel(b *testing.B) {
+	fixture := benchFixture()
+	newLoader := func(r io.Reader) Loader { return NewHostsLoader(r) }
+	b.SetBytes(int64(len(fixture)))
+	b.ResetTimer()
+	for range b.N {
+		l := NewParallelLoader(strings.NewReader(string(fixture)), newLoader)
+		for l.Load() {
+		}
+		l.Close()
+	}
+}
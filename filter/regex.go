@@ -0,0 +1,122 @@
+package filter
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// RegexLoader reads filters from a Pi-hole regex list, one pattern per line.
+type RegexLoader struct {
+	p    *RegexParser
+	name string
+	exc  bool
+
+	f   Filter
+	err error
+}
+
+// NewRegexLoader returns a RegexLoader that reads from r.
+func NewRegexLoader(r io.Reader) *RegexLoader {
+	p := NewRegexParser(r)
+	return &RegexLoader{p: p}
+}
+
+func (l *RegexLoader) SetName(name string) {
+	l.name = name
+}
+
+func (l *RegexLoader) SetException(exc bool) {
+	l.exc = exc
+}
+
+func (l *RegexLoader) Load() bool {
+	if !l.p.Parse() {
+		l.f = Filter{}
+		l.err = l.p.Err
+		return false
+	}
+
+	_, err := CompilePattern(KindRegex, l.p.Pattern)
+	if err != nil {
+		err = &ResourceError{
+			Name:   l.name,
+			Line:   l.p.Line,
+			Column: l.p.Column,
+			Text:   l.p.text(),
+			Err:    err,
+		}
+	}
+
+	l.f = Filter{
+		Exception: l.exc,
+		Kind:      KindRegex,
+		Pattern:   l.p.Pattern,
+		Name:      l.name,
+		Line:      l.p.Line,
+	}
+	l.err = err
+	return true
+}
+
+func (l *RegexLoader) Filter() Filter { return l.f }
+func (l *RegexLoader) Err() error     { return l.err }
+
+// RegexParser splits a regex list into patterns without compiling them.
+type RegexParser struct {
+	Line    int
+	Column  int
+	Pattern string
+	Err     error
+
+	s    *bufio.Scanner
+	lnum int
+}
+
+// NewRegexParser returns a RegexParser that reads from r.
+func NewRegexParser(r io.Reader) *RegexParser {
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanLines)
+	return &RegexParser{s: s}
+}
+
+func (p *RegexParser) Parse() bool {
+	for p.s.Scan() {
+		p.lnum++
+
+		line := p.s.Bytes()
+		pattern := ParseRegexLine(line)
+		if pattern != "" {
+			p.Line = p.lnum
+			p.Column = len(line) - len(bytes.TrimLeft(line, " \t")) + 1
+			p.Pattern = pattern
+			p.Err = nil
+			return true
+		}
+	}
+
+	p.Line = p.lnum
+	p.Column = 0
+	p.Pattern = ""
+	p.Err = p.s.Err()
+	return false
+}
+
+func (p *RegexParser) text() string {
+	return string(p.s.Bytes())
+}
+
+func ParseRegexLine(line []byte) string {
+	lo := 0
+	for ; lo < len(line) && (line[lo] == ' ' || line[lo] == '\t'); lo++ {
+	}
+	if lo < len(line) && line[lo] == '#' {
+		return ""
+	}
+
+	hi := len(line)
+	for ; hi > lo && (line[hi-1] == ' ' || line[hi-1] == '\t'); hi-- {
+	}
+
+	return string(line[lo:hi])
+}
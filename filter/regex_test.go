@@ -0,0 +1,76 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegexLoaderLoadNormal(t *testing.T) {
+	r := strings.NewReader("# Pi-hole regex list\n^ad[0-9]*\\.\n(^|\\.)tracker\\.example$\n")
+	l := NewRegexLoader(r)
+	HelpLoaderTest(t, l, true, Filter{Kind: KindRegex, Pattern: `^ad[0-9]*\.`, Line: 2}, false)
+	HelpLoaderTest(t, l, true, Filter{Kind: KindRegex, Pattern: `(^|\.)tracker\.example$`, Line: 3}, false)
+	HelpLoaderTest(t, l, false, Filter{}, false)
+}
+
+func TestRegexLoaderLoadException(t *testing.T) {
+	r := strings.NewReader("^good\\.\n")
+	l := NewRegexLoader(r)
+	l.SetException(true)
+	HelpLoaderTest(t, l, true, Filter{Exception: true, Kind: KindRegex, Pattern: `^good\.`, Line: 1}, false)
+	HelpLoaderTest(t, l, false, Filter{}, false)
+}
+
+func TestRegexLoaderSyntaxError(t *testing.T) {
+	r := strings.NewReader("\n\t^ad(\n")
+	l := NewRegexLoader(r)
+
+	HelpLoaderTest(t, l, true, Filter{Kind: KindRegex, Pattern: "^ad(", Line: 2}, true)
+	HelpResourceErrorTest(t, "l.Err()", l.Err(), "", 2)
+
+	resErr := l.Err().(*ResourceError)
+	if resErr.Column != 2 || resErr.Text != "\t^ad(" {
+		t.Errorf("l.Err(): expected column 2 and the line text, got %d and %q", resErr.Column, resErr.Text)
+	}
+
+	HelpLoaderTest(t, l, false, Filter{}, false)
+}
+
+func TestParseRegexLine(t *testing.T) {
+	tt := []struct {
+		in   []byte
+		want string
+	}{
+		{
+			in:   nil,
+			want: "",
+		},
+		{
+			in:   []byte("   "),
+			want: "",
+		},
+		{
+			in:   []byte("# comment"),
+			want: "",
+		},
+		{
+			in:   []byte("\t# comment"),
+			want: "",
+		},
+		{
+			in:   []byte("  ^ad[0-9]*\\.  "),
+			want: `^ad[0-9]*\.`,
+		},
+		{
+			in:   []byte("^a#b$"),
+			want: "^a#b$",
+		},
+	}
+
+	for _, tc := range tt {
+		got := ParseRegexLine(tc.in)
+		if got != tc.want {
+			t.Errorf("%q: expected %q, got %q", tc.in, tc.want, got)
+		}
+	}
+}
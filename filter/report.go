@@ -0,0 +1,224 @@
+package filter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ErrorKind classifies a load error in a Report.
+type ErrorKind string
+
+const (
+	ErrorKindHostsIP         ErrorKind = "HostsIPError"
+	ErrorKindIDNA            ErrorKind = "IDNAError"
+	ErrorKindMissingHostname ErrorKind = "MissingHostname"
+	ErrorKindLineTooLong     ErrorKind = "LineTooLong"
+	ErrorKindDomainSyntax    ErrorKind = "DomainSyntaxError"
+	ErrorKindPattern         ErrorKind = "PatternError"
+	ErrorKindOther           ErrorKind = "Error"
+)
+
+func ErrorKindOf(err error) ErrorKind {
+	var ipErr *HostsIPError
+	var idnaErr *IDNAError
+	var synErr *DomainSyntaxError
+	var patErr *PatternError
+	switch {
+	case errors.As(err, &ipErr):
+		return ErrorKindHostsIP
+	case errors.As(err, &idnaErr):
+		return ErrorKindIDNA
+	case errors.Is(err, ErrMissingHostname):
+		return ErrorKindMissingHostname
+	case errors.Is(err, bufio.ErrTooLong):
+		return ErrorKindLineTooLong
+	case errors.As(err, &synErr):
+		return ErrorKindDomainSyntax
+	case errors.As(err, &patErr):
+		return ErrorKindPattern
+	default:
+		return ErrorKindOther
+	}
+}
+
+// Report collects per-source counts and errors from a load.
+type Report struct {
+	Sources []ReportSource `json:"sources"`
+	Errors  []ReportError  `json:"errors"`
+
+	idx     map[string]int
+	display DisplayMode
+}
+
+// ReportSource holds the counts for one source in a Report.
+type ReportSource struct {
+	Name    string `json:"name"`
+	Filters int    `json:"filters"`
+	Errors  int    `json:"errors"`
+}
+
+// ReportError is one load error in a Report.
+type ReportError struct {
+	Source  string    `json:"source"`
+	Line    int       `json:"line,omitempty"`
+	Column  int       `json:"column,omitempty"`
+	Kind    ErrorKind `json:"kind"`
+	Message string    `json:"message"`
+	Text    string    `json:"text,omitempty"`
+	Domain  string    `json:"domain,omitempty"`
+}
+
+// NewReport returns an empty Report.
+func NewReport() *Report {
+	return &Report{
+		Sources: []ReportSource{},
+		Errors:  []ReportError{},
+		idx:     map[string]int{},
+	}
+}
+
+// SetDisplayMode selects how the Domain of each ReportError is shown.
+func (r *Report) SetDisplayMode(mode DisplayMode) {
+	r.display = mode
+}
+
+func (r *Report) Add(f Filter, err error) {
+	name := f.Name
+	var resErr *ResourceError
+	if errors.As(err, &resErr) {
+		name = resErr.Name
+	}
+
+	src := r.source(name)
+	if err == nil {
+		src.Filters++
+		return
+	}
+	src.Errors++
+
+	re := ReportError{
+		Source:  name,
+		Kind:    ErrorKindOf(err),
+		Message: err.Error(),
+	}
+	if f.Domain != "" {
+		re.Domain = r.display.Format(f.Domain)
+	}
+	if resErr != nil {
+		re.Line = resErr.Line
+		re.Column = resErr.Column
+		re.Text = resErr.Text
+		re.Message = resErr.Err.Error()
+	}
+	r.Errors = append(r.Errors, re)
+}
+
+func (r *Report) source(name string) *ReportSource {
+	i, ok := r.idx[name]
+	if !ok {
+		i = len(r.Sources)
+		r.idx[name] = i
+		r.Sources = append(r.Sources, ReportSource{Name: name})
+	}
+	return &r.Sources[i]
+}
+
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r *Report) WriteSARIF(w io.Writer) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "admasq", Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+
+	seen := map[ErrorKind]bool{}
+	for _, e := range r.Errors {
+		if !seen[e.Kind] {
+			seen[e.Kind] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: string(e.Kind)})
+		}
+
+		loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: e.Source}}
+		if e.Line > 0 {
+			loc.Region = &sarifRegion{StartLine: e.Line, StartColumn: e.Column}
+			if e.Text != "" {
+				loc.Region.Snippet = &sarifMessage{Text: e.Text}
+			}
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    string(e.Kind),
+			Level:     "error",
+			Message:   sarifMessage{Text: e.Message},
+			Locations: []sarifLocation{{PhysicalLocation: loc}},
+		})
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int           `json:"startLine"`
+	StartColumn int           `json:"startColumn,omitempty"`
+	Snippet     *sarifMessage `json:"snippet,omitempty"`
+}
@@ -0,0 +1,183 @@
+package filter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func HelpReport() *Report {
+	r := NewReport()
+	l := NewMultiLoader(HelpMultiSources()...)
+	defer l.Close()
+	for l.Load() {
+		r.Add(l.Filter(), l.Err())
+	}
+	return r
+}
+
+func TestErrorKindOf(t *testing.T) {
+	tt := []struct {
+		inErr    error
+		wantKind ErrorKind
+	}{
+		{inErr: &ResourceError{Err: &HostsIPError{}}, wantKind: ErrorKindHostsIP},
+		{inErr: &ResourceError{Err: &IDNAError{}}, wantKind: ErrorKindIDNA},
+		{inErr: &ResourceError{Err: ErrMissingHostname}, wantKind: ErrorKindMissingHostname},
+		{inErr: &ResourceError{Err: bufio.ErrTooLong}, wantKind: ErrorKindLineTooLong},
+		{inErr: &ResourceError{Err: &DomainSyntaxError{}}, wantKind: ErrorKindDomainSyntax},
+		{inErr: &ResourceError{Err: &PatternError{}}, wantKind: ErrorKindPattern},
+		{inErr: errors.New("test"), wantKind: ErrorKindOther},
+	}
+
+	for _, tc := range tt {
+		gotKind := ErrorKindOf(tc.inErr)
+		if gotKind != tc.wantKind {
+			t.Errorf("%v: expected %q, got %q", tc.inErr, tc.wantKind, gotKind)
+		}
+	}
+}
+
+func TestReportAdd(t *testing.T) {
+	r := HelpReport()
+
+	wantSources := []ReportSource{
+		{Name: "hosts.txt", Filters: 1, Errors: 1},
+		{Name: "broken.txt", Filters: 0, Errors: 1},
+		{Name: "simple.txt", Filters: 1, Errors: 1},
+	}
+	if !reflect.DeepEqual(r.Sources, wantSources) {
+		t.Errorf("r.Sources: expected %v, got %v", wantSources, r.Sources)
+	}
+
+	tt := []ReportError{
+		{Source: "hosts.txt", Line: 2, Column: 1, Kind: ErrorKindHostsIP, Text: "192.168.0.1 2.example.com"},
+		{Source: "broken.txt", Line: 0, Kind: ErrorKindOther},
+		{Source: "simple.txt", Line: 2, Column: 1, Kind: ErrorKindIDNA, Text: "--.com", Domain: "--.com"},
+	}
+	if len(r.Errors) != len(tt) {
+		t.Fatalf("len(r.Errors): expected %d, got %d", len(tt), len(r.Errors))
+	}
+	for i, tc := range tt {
+		got := r.Errors[i]
+		got.Message = ""
+		if got != tc {
+			t.Errorf("r.Errors[%d]: expected %v, got %v", i, tc, got)
+		}
+	}
+}
+
+func TestReportDisplayMode(t *testing.T) {
+	tt := []struct {
+		mode DisplayMode
+		want string
+	}{
+		{mode: DisplayASCII, want: "xn--t8jx73hngb.com"},
+		{mode: DisplayUnicode, want: "お名前.com"},
+		{mode: DisplayBoth, want: "お名前.com (xn--t8jx73hngb.com)"},
+	}
+
+	for _, tc := range tt {
+		r := NewReport()
+		r.SetDisplayMode(tc.mode)
+		r.Add(Filter{Domain: "xn--t8jx73hngb.com", Name: "a.txt"}, &ResourceError{Name: "a.txt", Line: 1, Err: errors.New("test")})
+		if got := r.Errors[0].Domain; got != tc.want {
+			t.Errorf("%d: expected %q, got %q", tc.mode, tc.want, got)
+		}
+	}
+}
+
+func TestReportWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := HelpReport().WriteJSON(&buf)
+	if err != nil {
+		t.Fatalf("err: expected nil, got %v", err)
+	}
+
+	var got struct {
+		Sources []ReportSource `json:"sources"`
+		Errors  []ReportError  `json:"errors"`
+	}
+	err = json.Unmarshal(buf.Bytes(), &got)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(got.Sources) != 3 {
+		t.Errorf("len(sources): expected 3, got %d", len(got.Sources))
+	}
+
+	tt := []struct {
+		wantSource string
+		wantLine   int
+		wantKind   ErrorKind
+	}{
+		{wantSource: "hosts.txt", wantLine: 2, wantKind: ErrorKindHostsIP},
+		{wantSource: "broken.txt", wantLine: 0, wantKind: ErrorKindOther},
+		{wantSource: "simple.txt", wantLine: 2, wantKind: ErrorKindIDNA},
+	}
+	if len(got.Errors) != len(tt) {
+		t.Fatalf("len(errors): expected %d, got %d", len(tt), len(got.Errors))
+	}
+	for i, tc := range tt {
+		e := got.Errors[i]
+		if e.Source != tc.wantSource {
+			t.Errorf("errors[%d].source: expected %q, got %q", i, tc.wantSource, e.Source)
+		}
+		if e.Line != tc.wantLine {
+			t.Errorf("errors[%d].line: expected %d, got %d", i, tc.wantLine, e.Line)
+		}
+		if e.Kind != tc.wantKind {
+			t.Errorf("errors[%d].kind: expected %q, got %q", i, tc.wantKind, e.Kind)
+		}
+	}
+}
+
+func TestReportWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	err := HelpReport().WriteSARIF(&buf)
+	if err != nil {
+		t.Fatalf("err: expected nil, got %v", err)
+	}
+
+	var got sarifLog
+	err = json.Unmarshal(buf.Bytes(), &got)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Version != "2.1.0" {
+		t.Errorf("version: expected %q, got %q", "2.1.0", got.Version)
+	}
+	if len(got.Runs) != 1 {
+		t.Fatalf("len(runs): expected 1, got %d", len(got.Runs))
+	}
+
+	run := got.Runs[0]
+	wantRules := []sarifRule{{ID: "HostsIPError"}, {ID: "Error"}, {ID: "IDNAError"}}
+	if !reflect.DeepEqual(run.Tool.Driver.Rules, wantRules) {
+		t.Errorf("rules: expected %v, got %v", wantRules, run.Tool.Driver.Rules)
+	}
+
+	tt := []struct {
+		wantURI    string
+		wantRegion *sarifRegion
+	}{
+		{wantURI: "hosts.txt", wantRegion: &sarifRegion{StartLine: 2, StartColumn: 1, Snippet: &sarifMessage{Text: "192.168.0.1 2.example.com"}}},
+		{wantURI: "broken.txt", wantRegion: nil},
+		{wantURI: "simple.txt", wantRegion: &sarifRegion{StartLine: 2, StartColumn: 1, Snippet: &sarifMessage{Text: "--.com"}}},
+	}
+	if len(run.Results) != len(tt) {
+		t.Fatalf("len(results): expected %d, got %d", len(tt), len(run.Results))
+	}
+	for i, tc := range tt {
+		loc := run.Results[i].Locations[0].PhysicalLocation
+		if loc.ArtifactLocation.URI != tc.wantURI {
+			t.Errorf("results[%d] uri: expected %q, got %q", i, tc.wantURI, loc.ArtifactLocation.URI)
+		}
+		if !reflect.DeepEqual(loc.Region, tc.wantRegion) {
+			t.Errorf("results[%d] region: expected %+v, got %+v", i, tc.wantRegion, loc.Region)
+		}
+	}
+}
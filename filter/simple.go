@@ -0,0 +1,150 @@
+package filter
+
+import (
+	"bufio"
+	"io"
+)
+
+// SimpleLoader reads filters from a list with one domain per line.
+type SimpleLoader struct {
+	p    *SimpleParser
+	name string
+	idna *IDNAProfile
+	syn  SyntaxMode
+	exc  bool
+
+	f   Filter
+	err error
+}
+
+// NewSimpleLoader returns a SimpleLoader that reads from r.
+func NewSimpleLoader(r io.Reader) *SimpleLoader {
+	p := NewSimpleParser(r)
+	return &SimpleLoader{p: p}
+}
+
+func (l *SimpleLoader) SetIDNAProfile(p *IDNAProfile) {
+	l.idna = p
+}
+
+func (l *SimpleLoader) SetSyntaxMode(mode SyntaxMode) {
+	l.syn = mode
+}
+
+func (l *SimpleLoader) SetName(name string) {
+	l.name = name
+}
+
+func (l *SimpleLoader) SetInterner(in *Interner) {
+	l.p.SetInterner(in)
+}
+
+func (l *SimpleLoader) SetException(exc bool) {
+	l.exc = exc
+}
+
+func (l *SimpleLoader) Load() bool {
+	if !l.p.Parse() {
+		l.f = Filter{}
+		l.err = l.p.Err
+		return false
+	}
+
+	var err error
+	domain := l.p.Domain
+	domain, err = normalizeDomain(domain, l.syn, l.idna)
+	if err != nil {
+		err = &ResourceError{
+			Name:   l.name,
+			Line:   l.p.Line,
+			Column: l.p.Column + syntaxOffset(err),
+			Text:   l.p.text(),
+			Err:    err,
+		}
+	}
+
+	l.f = Filter{
+		Exception: l.exc,
+		Domain:    domain,
+		Name:      l.name,
+		Line:      l.p.Line,
+	}
+	l.err = err
+	return true
+}
+
+func (l *SimpleLoader) Filter() Filter { return l.f }
+func (l *SimpleLoader) Err() error     { return l.err }
+
+// SimpleParser splits a simple list into domains without validating them.
+type SimpleParser struct {
+	Line   int
+	Column int
+	Domain string
+	Err    error
+
+	s    *bufio.Scanner
+	lnum int
+	in   *Interner
+}
+
+// NewSimpleParser returns a SimpleParser that reads from r.
+func NewSimpleParser(r io.Reader) *SimpleParser {
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanLines)
+	return &SimpleParser{s: s}
+}
+
+func (p *SimpleParser) SetInterner(in *Interner) {
+	p.in = in
+}
+
+func (p *SimpleParser) Parse() bool {
+	for p.s.Scan() {
+		p.lnum++
+
+		line := p.s.Bytes()
+		lo, hi := simpleLineBounds(line)
+		if hi > lo {
+			p.Line = p.lnum
+			p.Column = lo + 1
+			p.Domain = p.in.Intern(line[lo:hi])
+			p.Err = nil
+			return true
+		}
+	}
+
+	p.Line = p.lnum
+	p.Column = 0
+	p.Domain = ""
+	p.Err = p.s.Err()
+	return false
+}
+
+func (p *SimpleParser) text() string {
+	return string(p.s.Bytes())
+}
+
+func ParseSimpleLine(line []byte) string {
+	return string(ParseSimpleLineBytes(line))
+}
+
+func ParseSimpleLineBytes(line []byte) []byte {
+	lo, hi := simpleLineBounds(line)
+	return line[lo:hi]
+}
+
+// simpleLineBounds returns the byte offsets of the domain in line.
+func simpleLineBounds(line []byte) (int, int) {
+	lo := 0
+	for ; lo < len(line) && (line[lo] == ' ' || line[lo] == '\t'); lo++ {
+	}
+
+	hi := lo
+	for i := lo; i < len(line) && line[i] != '#'; i++ {
+		if line[i] != ' ' && line[i] != '\t' {
+			hi = i + 1
+		}
+	}
+	return lo, hi
+}
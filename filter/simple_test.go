@@ -1,7 +1,9 @@
-package main
+package filter
 
 import (
+	"bytes"
 	"errors"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -15,8 +17,8 @@ func TestSimpleLoaderLoadEmpty(t *testing.T) {
 func TestSimpleLoaderLoadNormal(t *testing.T) {
 	r := strings.NewReader("1.example.com\n2.example.com\n")
 	l := NewSimpleLoader(r)
-	HelpLoaderTest(t, l, true, Filter{Domain: "1.example.com"}, false)
-	HelpLoaderTest(t, l, true, Filter{Domain: "2.example.com"}, false)
+	HelpLoaderTest(t, l, true, Filter{Domain: "1.example.com", Line: 1}, false)
+	HelpLoaderTest(t, l, true, Filter{Domain: "2.example.com", Line: 2}, false)
 	HelpLoaderTest(t, l, false, Filter{}, false)
 }
 
@@ -24,15 +26,33 @@ func TestSimpleLoaderLoadException(t *testing.T) {
 	r := strings.NewReader("1.example.com\n2.example.com\n")
 	l := NewSimpleLoader(r)
 	l.SetException(true)
-	HelpLoaderTest(t, l, true, Filter{Exception: true, Domain: "1.example.com"}, false)
-	HelpLoaderTest(t, l, true, Filter{Exception: true, Domain: "2.example.com"}, false)
+	HelpLoaderTest(t, l, true, Filter{Exception: true, Domain: "1.example.com", Line: 1}, false)
+	HelpLoaderTest(t, l, true, Filter{Exception: true, Domain: "2.example.com", Line: 2}, false)
+	HelpLoaderTest(t, l, false, Filter{}, false)
+}
+
+func TestSimpleLoaderLoadName(t *testing.T) {
+	r := strings.NewReader("1.example.com\n--.com\n")
+	l := NewSimpleLoader(r)
+	l.SetName("simple.txt")
+	HelpLoaderTest(t, l, true, Filter{Domain: "1.example.com", Name: "simple.txt", Line: 1}, false)
+	HelpLoaderTest(t, l, true, Filter{Domain: "--.com", Name: "simple.txt", Line: 2}, true)
+	HelpResourceErrorTest(t, "l.Err()", l.Err(), "simple.txt", 2)
+	HelpLoaderTest(t, l, false, Filter{}, false)
+}
+
+func TestSimpleLoaderIDNAProfile(t *testing.T) {
+	r := strings.NewReader("_dmarc.example.com\n")
+	l := NewSimpleLoader(r)
+	l.SetIDNAProfile(IDNAUnderscore)
+	HelpLoaderTest(t, l, true, Filter{Domain: "_dmarc.example.com", Line: 1}, false)
 	HelpLoaderTest(t, l, false, Filter{}, false)
 }
 
 func TestSimpleLoaderIDNANormal(t *testing.T) {
 	r := strings.NewReader("お名前.com\n")
 	l := NewSimpleLoader(r)
-	HelpLoaderTest(t, l, true, Filter{Domain: "xn--t8jx73hngb.com"}, false)
+	HelpLoaderTest(t, l, true, Filter{Domain: "xn--t8jx73hngb.com", Line: 1}, false)
 	HelpLoaderTest(t, l, false, Filter{}, false)
 }
 
@@ -40,12 +60,28 @@ func TestSimpleLoaderIDNAError(t *testing.T) {
 	r := strings.NewReader("--.com\n")
 	l := NewSimpleLoader(r)
 
-	HelpLoaderTest(t, l, true, Filter{Domain: "--.com"}, true)
+	HelpLoaderTest(t, l, true, Filter{Domain: "--.com", Line: 1}, true)
 	HelpResourceErrorTest(t, "l.Err()", l.Err(), "", 1)
 
 	HelpLoaderTest(t, l, false, Filter{}, false)
 }
 
+func TestSimpleLoaderSyntaxColumn(t *testing.T) {
+	r := strings.NewReader("  ads..example.com\n")
+	l := NewSimpleLoader(r)
+	l.SetSyntaxMode(SyntaxStrict)
+
+	HelpLoaderTest(t, l, true, Filter{Domain: "ads..example.com", Line: 1}, true)
+
+	resErr := l.Err().(*ResourceError)
+	if resErr.Column != 7 {
+		t.Errorf("l.Err().Column: expected 7, got %d", resErr.Column)
+	}
+	if resErr.Text != "  ads..example.com" {
+		t.Errorf("l.Err().Text: expected %q, got %q", "  ads..example.com", resErr.Text)
+	}
+}
+
 func TestSimpleParserParseEmpty(t *testing.T) {
 	r := strings.NewReader("")
 	p := NewSimpleParser(r)
@@ -150,3 +186,45 @@ func TestParseSimpleLine(t *testing.T) {
 		}
 	}
 }
+
+func TestParseSimpleLineBytes(t *testing.T) {
+	line := []byte("  example.com  # comment")
+	got := ParseSimpleLineBytes(line)
+	if string(got) != "example.com" {
+		t.Errorf("expected %q, got %q", "example.com", got)
+	}
+	if &got[0] != &line[2] {
+		t.Error("expected a sub-slice of the input line")
+	}
+}
+
+func TestSimpleLoaderInterner(t *testing.T) {
+	in := NewInterner()
+	l := NewSimpleLoader(strings.NewReader("a.example.com\na.example.com\nb.example.com\n"))
+	l.SetInterner(in)
+
+	HelpLoaderTest(t, l, true, Filter{Domain: "a.example.com", Line: 1}, false)
+	HelpLoaderTest(t, l, true, Filter{Domain: "a.example.com", Line: 2}, false)
+	HelpLoaderTest(t, l, true, Filter{Domain: "b.example.com", Line: 3}, false)
+	HelpLoaderTest(t, l, false, Filter{}, false)
+
+	if in.Len() != 2 {
+		t.Errorf("in.Len(): expected 2, got %d", in.Len())
+	}
+}
+
+func BenchmarkSimpleLoaderPerLine(b *testing.B) {
+	var data []byte
+	for i := range b.N {
+		data = append(data, "host"...)
+		data = strconv.AppendInt(data, int64(i%1000), 10)
+		data = append(data, ".example.com\n"...)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	l := NewSimpleLoader(bytes.NewReader(data))
+	l.SetInterner(NewInterner())
+	for l.Load() {
+	}
+}
@@ -0,0 +1,188 @@
+package filter
+
+import (
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// SyntaxMode selects how strictly domain syntax is checked.
+type SyntaxMode int
+
+const (
+	SyntaxOff SyntaxMode = iota
+	SyntaxStrict
+	SyntaxRepair
+)
+
+func (m SyntaxMode) check(s string) (string, error) {
+	switch m {
+	case SyntaxStrict:
+		return s, ValidateDomain(s)
+	case SyntaxRepair:
+		return RepairDomain(s)
+	default:
+		return s, nil
+	}
+}
+
+func normalizeDomain(s string, mode SyntaxMode, p *IDNAProfile) (string, error) {
+	s, err := mode.check(s)
+	if err != nil {
+		return s, err
+	}
+	return p.ToASCII(s)
+}
+
+func syntaxOffset(err error) int {
+	var synErr *DomainSyntaxError
+	if errors.As(err, &synErr) {
+		return synErr.Offset
+	}
+	return 0
+}
+
+// DomainSyntaxReason names the syntax rule a domain broke.
+type DomainSyntaxReason string
+
+const (
+	ReasonEmpty       DomainSyntaxReason = "empty name"
+	ReasonNameLength  DomainSyntaxReason = "name longer than 253 bytes"
+	ReasonEmptyLabel  DomainSyntaxReason = "empty label"
+	ReasonLabelLength DomainSyntaxReason = "label longer than 63 bytes"
+	ReasonSpace       DomainSyntaxReason = "whitespace or control character"
+	ReasonIPLiteral   DomainSyntaxReason = "IP address literal"
+	ReasonURLScheme   DomainSyntaxReason = "URL scheme"
+	ReasonURLPath     DomainSyntaxReason = "URL path, query or fragment"
+	ReasonPort        DomainSyntaxReason = "port or userinfo"
+)
+
+func ValidateDomain(s string) error {
+	if len(s) <= 0 {
+		return &DomainSyntaxError{Domain: s, Reason: ReasonEmpty}
+	}
+
+	if strings.Contains(s, "://") {
+		return &DomainSyntaxError{Domain: s, Reason: ReasonURLScheme}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c <= ' ' || c == 0x7f:
+			return &DomainSyntaxError{Domain: s, Reason: ReasonSpace, Offset: i}
+		case c == '/' || c == '?' || c == '#':
+			return &DomainSyntaxError{Domain: s, Reason: ReasonURLPath, Offset: i}
+		}
+	}
+
+	if isIPLiteral(s) {
+		return &DomainSyntaxError{Domain: s, Reason: ReasonIPLiteral}
+	}
+
+	if i := strings.IndexAny(s, ":@"); i >= 0 {
+		return &DomainSyntaxError{Domain: s, Reason: ReasonPort, Offset: i}
+	}
+
+	name := strings.TrimSuffix(s, ".")
+	if len(name) > 253 && isASCII(name) {
+		return &DomainSyntaxError{Domain: s, Reason: ReasonNameLength, Offset: 253}
+	}
+	total := -1
+	for start, i := 0, 0; i <= len(name); i++ {
+		if i < len(name) && name[i] != '.' {
+			continue
+		}
+		total += 1 + aLabelLen(name[start:i])
+		if total > 253 {
+			return &DomainSyntaxError{Domain: s, Reason: ReasonNameLength, Offset: start}
+		}
+		start = i + 1
+	}
+
+	start := 0
+	for i := 0; i <= len(name); i++ {
+		if i < len(name) && name[i] != '.' {
+			continue
+		}
+		switch n := aLabelLen(name[start:i]); {
+		case n <= 0:
+			return &DomainSyntaxError{Domain: s, Reason: ReasonEmptyLabel, Offset: start}
+		case n > 63:
+			return &DomainSyntaxError{Domain: s, Reason: ReasonLabelLength, Offset: start}
+		}
+		start = i + 1
+	}
+	return nil
+}
+
+// aLabelLen returns the length of label once converted to ASCII, which is
+// what the DNS length limits apply to.
+func aLabelLen(label string) int {
+	if isASCII(label) {
+		return len(label)
+	}
+	a, err := idna.Punycode.ToASCII(label)
+	if err != nil {
+		return len(label)
+	}
+	return len(a)
+}
+
+func RepairDomain(s string) (string, error) {
+	host := strings.TrimSpace(s)
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+	if i := strings.LastIndexByte(host, '@'); i >= 0 {
+		host = host[i+1:]
+	}
+
+	if strings.HasPrefix(host, "[") {
+		if i := strings.IndexByte(host, ']'); i >= 0 {
+			host = host[:i+1]
+		}
+	} else if i := strings.LastIndexByte(host, ':'); i >= 0 && strings.Count(host, ":") == 1 {
+		if _, err := strconv.ParseUint(host[i+1:], 10, 16); err == nil {
+			host = host[:i]
+		}
+	}
+
+	if err := ValidateDomain(host); err != nil {
+		if synErr, ok := err.(*DomainSyntaxError); ok {
+			synErr.Domain = s
+			synErr.Offset += strings.Index(s, host)
+		}
+		return s, err
+	}
+	return host, nil
+}
+
+func isIPLiteral(s string) bool {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	_, err := netip.ParseAddr(s)
+	return err == nil
+}
+
+// DomainSyntaxError reports a domain that breaks a syntax rule.
+type DomainSyntaxError struct {
+	Domain string
+	Reason DomainSyntaxReason
+	Offset int
+}
+
+func (e *DomainSyntaxError) Error() string {
+	b := []byte("domain ")
+	b = strconv.AppendQuote(b, e.Domain)
+	b = append(b, ": "...)
+	b = append(b, e.Reason...)
+	b = append(b, " at offset "...)
+	b = strconv.AppendInt(b, int64(e.Offset), 10)
+	return string(b)
+}
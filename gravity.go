@@ -0,0 +1,567 @@
+package main
+
+import (
+	"database/sql"
+	"net/url"
+	"strconv"
+
+	"github.com/gcrtnst/admasq/filter"
+	_ "modernc.org/sqlite"
+)
+
+type Gravity struct {
+	Groups  []GravityGroup
+	Adlists []GravityAdlist
+	Domains []GravityDomain
+	Entries []GravityEntry
+}
+
+type GravityGroup struct {
+	ID          int64
+	Name        string
+	Enabled     bool
+	Description string
+}
+
+type GravityAdlist struct {
+	ID      int64
+	Address string
+	Enabled bool
+	Comment string
+	Groups  []int64
+}
+
+type GravityDomain struct {
+	ID      int64
+	Type    GravityDomainType
+	Domain  string
+	Enabled bool
+	Comment string
+	Groups  []int64
+}
+
+type GravityEntry struct {
+	Domain string
+	Adlist int64
+}
+
+type GravityDomainType int
+
+const (
+	GravityExactAllow GravityDomainType = iota
+	GravityExactDeny
+	GravityRegexAllow
+	GravityRegexDeny
+)
+
+func (t GravityDomainType) Exception() bool {
+	return t == GravityExactAllow || t == GravityRegexAllow
+}
+
+func (t GravityDomainType) Kind() filter.Kind {
+	if t == GravityRegexAllow || t == GravityRegexDeny {
+		return filter.KindRegex
+	}
+	return filter.KindDomain
+}
+
+func OpenGravity(path string) (*sql.DB, error) {
+	return sql.Open("sqlite", path)
+}
+
+func ReadGravity(db *sql.DB) (*Gravity, error) {
+	g := &Gravity{}
+
+	rows, err := db.Query(`SELECT id, name, enabled, IFNULL(description, '') FROM "group" ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var grp GravityGroup
+		if err := rows.Scan(&grp.ID, &grp.Name, &grp.Enabled, &grp.Description); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		g.Groups = append(g.Groups, grp)
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(`SELECT id, address, enabled, IFNULL(comment, '') FROM adlist ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var a GravityAdlist
+		if err := rows.Scan(&a.ID, &a.Address, &a.Enabled, &a.Comment); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		g.Adlists = append(g.Adlists, a)
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(`SELECT id, type, domain, enabled, IFNULL(comment, '') FROM domainlist ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var d GravityDomain
+		if err := rows.Scan(&d.ID, &d.Type, &d.Domain, &d.Enabled, &d.Comment); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		g.Domains = append(g.Domains, d)
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(`SELECT domain, adlist_id FROM gravity ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var e GravityEntry
+		if err := rows.Scan(&e.Domain, &e.Adlist); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		g.Entries = append(g.Entries, e)
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	adlistGroups, err := readGravityGroups(db, `SELECT adlist_id, group_id FROM adlist_by_group ORDER BY adlist_id, group_id`)
+	if err != nil {
+		return nil, err
+	}
+	for i := range g.Adlists {
+		g.Adlists[i].Groups = adlistGroups[g.Adlists[i].ID]
+	}
+
+	domainGroups, err := readGravityGroups(db, `SELECT domainlist_id, group_id FROM domainlist_by_group ORDER BY domainlist_id, group_id`)
+	if err != nil {
+		return nil, err
+	}
+	for i := range g.Domains {
+		g.Domains[i].Groups = domainGroups[g.Domains[i].ID]
+	}
+
+	return g, nil
+}
+
+func readGravityGroups(db *sql.DB, query string) (map[int64][]int64, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	m := map[int64][]int64{}
+	for rows.Next() {
+		var id, gid int64
+		if err := rows.Scan(&id, &gid); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m[id] = append(m[id], gid)
+	}
+	return m, closeRows(rows)
+}
+
+func closeRows(rows *sql.Rows) error {
+	err := rows.Err()
+	cerr := rows.Close()
+	if err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// GravityFromFilters converts fs for WriteGravity. Exact block domains from a
+// source named by a URL go into the gravity table under an adlist with that
+// address, so that pihole -g can download them again. Pi-hole cannot fetch
+// any other source, so its block domains go into domainlist as exact denies,
+// with the source name as comment.
+func GravityFromFilters(fs []filter.Filter) *Gravity {
+	g := &Gravity{}
+	adlists := map[string]int64{}
+	seen := map[GravityEntry]bool{}
+	denied := map[string]bool{}
+	for _, f := range fs {
+		if f.Kind == filter.KindDomain && !f.Exception && isAdlistAddress(f.Name) {
+			id, ok := adlists[f.Name]
+			if !ok {
+				id = int64(len(g.Adlists) + 1)
+				adlists[f.Name] = id
+				g.Adlists = append(g.Adlists, GravityAdlist{
+					ID:      id,
+					Address: f.Name,
+					Enabled: true,
+					Groups:  []int64{0},
+				})
+			}
+			e := GravityEntry{Domain: f.Domain, Adlist: id}
+			if !seen[e] {
+				seen[e] = true
+				g.Entries = append(g.Entries, e)
+			}
+			continue
+		}
+		if f.Kind == filter.KindDomain && !f.Exception {
+			if denied[f.Domain] {
+				continue
+			}
+			denied[f.Domain] = true
+		}
+
+		d := GravityDomain{
+			ID:      int64(len(g.Domains) + 1),
+			Domain:  f.Domain,
+			Enabled: true,
+			Groups:  []int64{0},
+		}
+
+		switch {
+		case f.Kind == filter.KindDomain && f.Exception:
+			d.Type = GravityExactAllow
+		case f.Kind == filter.KindDomain:
+			d.Type = GravityExactDeny
+			d.Comment = f.Name
+		case f.Exception:
+			d.Type = GravityRegexAllow
+		default:
+			d.Type = GravityRegexDeny
+		}
+
+		switch f.Kind {
+		case filter.KindRegex:
+			d.Domain = f.Pattern
+		case filter.KindGlob:
+			d.Domain = filter.GlobToRegex(f.Pattern)
+		}
+
+		g.Domains = append(g.Domains, d)
+	}
+	return g
+}
+
+// isAdlistAddress reports whether name is an address that pihole -g can
+// download.
+func isAdlistAddress(name string) bool {
+	u, err := url.Parse(name)
+	if err != nil || u.Opaque != "" {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return u.Host != ""
+	case "file":
+		return u.Path != ""
+	default:
+		return false
+	}
+}
+
+func WriteGravity(db *sql.DB, g *Gravity) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range gravitySchema {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	for _, grp := range g.Groups {
+		_, err := tx.Exec(`INSERT OR REPLACE INTO "group" (id, enabled, name, description) VALUES (?, ?, ?, ?)`,
+			grp.ID, grp.Enabled, grp.Name, grp.Description)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, a := range g.Adlists {
+		_, err := tx.Exec(`INSERT INTO adlist (id, address, enabled, comment) VALUES (?, ?, ?, ?)`,
+			a.ID, a.Address, a.Enabled, nullString(a.Comment))
+		if err != nil {
+			return &GravityEntryError{Table: "adlist", ID: a.ID, Err: err}
+		}
+		for _, gid := range a.Groups {
+			_, err := tx.Exec(`INSERT OR IGNORE INTO adlist_by_group (adlist_id, group_id) VALUES (?, ?)`, a.ID, gid)
+			if err != nil {
+				return &GravityEntryError{Table: "adlist_by_group", ID: a.ID, Err: err}
+			}
+		}
+	}
+
+	for _, d := range g.Domains {
+		res, err := tx.Exec(`INSERT OR IGNORE INTO domainlist (id, type, domain, enabled, comment) VALUES (?, ?, ?, ?, ?)`,
+			d.ID, d.Type, d.Domain, d.Enabled, nullString(d.Comment))
+		if err != nil {
+			return &GravityEntryError{Table: "domainlist", ID: d.ID, Err: err}
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return &GravityEntryError{Table: "domainlist", ID: d.ID, Err: err}
+		}
+		if n <= 0 {
+			continue
+		}
+		for _, gid := range d.Groups {
+			_, err := tx.Exec(`INSERT OR IGNORE INTO domainlist_by_group (domainlist_id, group_id) VALUES (?, ?)`, d.ID, gid)
+			if err != nil {
+				return &GravityEntryError{Table: "domainlist_by_group", ID: d.ID, Err: err}
+			}
+		}
+	}
+
+	for _, e := range g.Entries {
+		_, err := tx.Exec(`INSERT INTO gravity (domain, adlist_id) VALUES (?, ?)`, e.Domain, e.Adlist)
+		if err != nil {
+			return &GravityEntryError{Table: "gravity", ID: e.Adlist, Err: err}
+		}
+	}
+	_, err = tx.Exec(`UPDATE adlist SET number = (SELECT COUNT(*) FROM gravity WHERE gravity.adlist_id = adlist.id)`)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+var gravitySchema = []string{
+	`CREATE TABLE IF NOT EXISTS "group" (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		enabled BOOLEAN NOT NULL DEFAULT 1,
+		name TEXT UNIQUE NOT NULL,
+		date_added INTEGER NOT NULL DEFAULT (cast(strftime('%s', 'now') as int)),
+		date_modified INTEGER NOT NULL DEFAULT (cast(strftime('%s', 'now') as int)),
+		description TEXT
+	)`,
+	`INSERT OR IGNORE INTO "group" (id, enabled, name, description) VALUES (0, 1, 'Default', 'The default group')`,
+	`CREATE TABLE IF NOT EXISTS domainlist (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		type INTEGER NOT NULL DEFAULT 0,
+		domain TEXT NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT 1,
+		date_added INTEGER NOT NULL DEFAULT (cast(strftime('%s', 'now') as int)),
+		date_modified INTEGER NOT NULL DEFAULT (cast(strftime('%s', 'now') as int)),
+		comment TEXT,
+		UNIQUE(domain, type)
+	)`,
+	`CREATE TABLE IF NOT EXISTS adlist (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		address TEXT UNIQUE NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT 1,
+		date_added INTEGER NOT NULL DEFAULT (cast(strftime('%s', 'now') as int)),
+		date_modified INTEGER NOT NULL DEFAULT (cast(strftime('%s', 'now') as int)),
+		comment TEXT,
+		date_updated INTEGER,
+		number INTEGER NOT NULL DEFAULT 0,
+		invalid_domains INTEGER NOT NULL DEFAULT 0,
+		status INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE IF NOT EXISTS adlist_by_group (
+		adlist_id INTEGER NOT NULL REFERENCES adlist (id),
+		group_id INTEGER NOT NULL REFERENCES "group" (id),
+		PRIMARY KEY (adlist_id, group_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS domainlist_by_group (
+		domainlist_id INTEGER NOT NULL REFERENCES domainlist (id),
+		group_id INTEGER NOT NULL REFERENCES "group" (id),
+		PRIMARY KEY (domainlist_id, group_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS gravity (
+		domain TEXT NOT NULL,
+		adlist_id INTEGER NOT NULL REFERENCES adlist (id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS client (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		ip TEXT NOT NULL UNIQUE,
+		date_added INTEGER NOT NULL DEFAULT (cast(strftime('%s', 'now') as int)),
+		date_modified INTEGER NOT NULL DEFAULT (cast(strftime('%s', 'now') as int)),
+		comment TEXT
+	)`,
+	`CREATE TABLE IF NOT EXISTS client_by_group (
+		client_id INTEGER NOT NULL REFERENCES client (id),
+		group_id INTEGER NOT NULL REFERENCES "group" (id),
+		PRIMARY KEY (client_id, group_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS info (
+		property TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	)`,
+	`INSERT OR IGNORE INTO info (property, value) VALUES ('version', '15')`,
+	`CREATE VIEW IF NOT EXISTS vw_whitelist AS ` + gravityDomainView(GravityExactAllow),
+	`CREATE VIEW IF NOT EXISTS vw_blacklist AS ` + gravityDomainView(GravityExactDeny),
+	`CREATE VIEW IF NOT EXISTS vw_regex_whitelist AS ` + gravityDomainView(GravityRegexAllow),
+	`CREATE VIEW IF NOT EXISTS vw_regex_blacklist AS ` + gravityDomainView(GravityRegexDeny),
+	`CREATE VIEW IF NOT EXISTS vw_gravity AS
+		SELECT domain, adlist_by_group.group_id AS group_id
+		FROM gravity
+		LEFT JOIN adlist_by_group ON adlist_by_group.adlist_id = gravity.adlist_id
+		LEFT JOIN adlist ON adlist.id = gravity.adlist_id
+		LEFT JOIN "group" ON "group".id = adlist_by_group.group_id
+		WHERE adlist.enabled = 1 AND (adlist_by_group.group_id IS NULL OR "group".enabled = 1)`,
+	`CREATE VIEW IF NOT EXISTS vw_adlist AS
+		SELECT DISTINCT address, adlist.id AS id
+		FROM adlist
+		LEFT JOIN adlist_by_group ON adlist_by_group.adlist_id = adlist.id
+		LEFT JOIN "group" ON "group".id = adlist_by_group.group_id
+		WHERE adlist.enabled = 1 AND (adlist_by_group.group_id IS NULL OR "group".enabled = 1)
+		ORDER BY adlist.id`,
+}
+
+func gravityDomainView(t GravityDomainType) string {
+	return `SELECT domain, domainlist.id AS id, domainlist_by_group.group_id AS group_id
+		FROM domainlist
+		LEFT JOIN domainlist_by_group ON domainlist_by_group.domainlist_id = domainlist.id
+		LEFT JOIN "group" ON "group".id = domainlist_by_group.group_id
+		WHERE domainlist.enabled = 1 AND (domainlist_by_group.group_id IS NULL OR "group".enabled = 1)
+		AND domainlist.type = ` + strconv.Itoa(int(t)) + `
+		ORDER BY domainlist.id`
+}
+
+type GravityLoader struct {
+	g       *Gravity
+	group   *int64
+	adlists map[int64]GravityAdlist
+	enabled map[int64]bool
+	i       int
+	j       int
+
+	id  int64
+	f   filter.Filter
+	err error
+}
+
+func NewGravityLoader(g *Gravity) *GravityLoader {
+	adlists := make(map[int64]GravityAdlist, len(g.Adlists))
+	for _, a := range g.Adlists {
+		adlists[a.ID] = a
+	}
+	enabled := make(map[int64]bool, len(g.Groups))
+	for _, grp := range g.Groups {
+		enabled[grp.ID] = grp.Enabled
+	}
+	return &GravityLoader{g: g, adlists: adlists, enabled: enabled}
+}
+
+func (l *GravityLoader) SetGroup(id int64) {
+	l.group = &id
+}
+
+func (l *GravityLoader) Load() bool {
+	for l.i < len(l.g.Domains) {
+		d := l.g.Domains[l.i]
+		l.i++
+
+		if !d.Enabled || !l.active(d.Groups) {
+			continue
+		}
+
+		var err error
+		f := filter.Filter{
+			Exception: d.Type.Exception(),
+			Kind:      d.Type.Kind(),
+			Name:      "domainlist",
+		}
+		if f.Kind == filter.KindRegex {
+			f.Pattern = d.Domain
+			_, err = filter.CompilePattern(filter.KindRegex, d.Domain)
+		} else {
+			f.Domain, err = filter.IDNAToASCII(d.Domain)
+		}
+		if err != nil {
+			err = &filter.ResourceError{
+				Name: "domainlist",
+				Err:  &GravityEntryError{Table: "domainlist", ID: d.ID, Err: err},
+			}
+		}
+
+		l.id = d.ID
+		l.f = f
+		l.err = err
+		return true
+	}
+
+	for l.j < len(l.g.Entries) {
+		e := l.g.Entries[l.j]
+		l.j++
+
+		a, ok := l.adlists[e.Adlist]
+		if !ok || !a.Enabled || !l.active(a.Groups) {
+			continue
+		}
+
+		domain, err := filter.IDNAToASCII(e.Domain)
+		if err != nil {
+			err = &filter.ResourceError{
+				Name: a.Address,
+				Err:  &GravityEntryError{Table: "gravity", ID: a.ID, Err: err},
+			}
+		}
+
+		l.id = 0
+		l.f = filter.Filter{Domain: domain, Name: a.Address}
+		l.err = err
+		return true
+	}
+
+	l.id = 0
+	l.f = filter.Filter{}
+	l.err = nil
+	return false
+}
+
+func (l *GravityLoader) ID() int64 { return l.id }
+
+func (l *GravityLoader) active(groups []int64) bool {
+	if len(groups) <= 0 {
+		return l.group == nil
+	}
+
+	for _, gid := range groups {
+		if l.group != nil && gid != *l.group {
+			continue
+		}
+		if l.enabled[gid] {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *GravityLoader) Filter() filter.Filter { return l.f }
+func (l *GravityLoader) Err() error            { return l.err }
+
+type GravityEntryError struct {
+	Table string
+	ID    int64
+	Err   error
+}
+
+func (e *GravityEntryError) Error() string {
+	b := []byte(e.Table)
+	b = append(b, " entry "...)
+	b = strconv.AppendInt(b, e.ID, 10)
+	b = append(b, ": "...)
+	b = append(b, e.Err.Error()...)
+	return string(b)
+}
+
+func (e *GravityEntryError) Unwrap() error {
+	return e.Err
+}
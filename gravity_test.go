@@ -0,0 +1,248 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+func HelpGravityFixture(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := OpenGravity(filepath.Join(t.TempDir(), "gravity.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := WriteGravity(db, &Gravity{}); err != nil {
+		t.Fatal(err)
+	}
+
+	stmts := []string{
+		`INSERT INTO "group" (id, enabled, name, description) VALUES (1, 1, 'kids', 'Kids devices')`,
+		`INSERT INTO "group" (id, enabled, name) VALUES (2, 0, 'disabled')`,
+		`INSERT INTO adlist (id, address, enabled, comment) VALUES (1, 'https://example.com/hosts.txt', 1, 'Main list')`,
+		`INSERT INTO adlist (id, address, enabled) VALUES (2, 'https://example.org/list.txt', 0)`,
+		`INSERT INTO adlist_by_group (adlist_id, group_id) VALUES (1, 0), (1, 1), (2, 0)`,
+		`INSERT INTO domainlist (id, type, domain, enabled, comment) VALUES (1, 0, 'good.example.com', 1, 'allowed')`,
+		`INSERT INTO domainlist (id, type, domain, enabled) VALUES (2, 1, 'ads.example.com', 1)`,
+		`INSERT INTO domainlist (id, type, domain, enabled) VALUES (3, 2, '^allowed\.', 1)`,
+		`INSERT INTO domainlist (id, type, domain, enabled) VALUES (4, 3, '^ad[0-9]*\.', 1)`,
+		`INSERT INTO domainlist (id, type, domain, enabled) VALUES (5, 1, 'off.example.com', 0)`,
+		`INSERT INTO domainlist (id, type, domain, enabled) VALUES (6, 1, 'kids.example.com', 1)`,
+		`INSERT INTO domainlist (id, type, domain, enabled) VALUES (7, 1, 'nobody.example.com', 1)`,
+		`INSERT INTO domainlist_by_group (domainlist_id, group_id) VALUES (1, 0), (2, 0), (3, 0), (4, 0), (5, 0), (6, 1), (7, 2)`,
+		`INSERT INTO gravity (domain, adlist_id) VALUES ('tracker.example.com', 1), ('off.example.org', 2)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return db
+}
+
+func TestReadGravity(t *testing.T) {
+	db := HelpGravityFixture(t)
+
+	g, err := ReadGravity(db)
+	if err != nil {
+		t.Fatalf("err: expected nil, got %#v", err)
+	}
+
+	wantGroups := []GravityGroup{
+		{ID: 0, Name: "Default", Enabled: true, Description: "The default group"},
+		{ID: 1, Name: "kids", Enabled: true, Description: "Kids devices"},
+		{ID: 2, Name: "disabled", Enabled: false},
+	}
+	if !reflect.DeepEqual(g.Groups, wantGroups) {
+		t.Errorf("g.Groups: expected %v, got %v", wantGroups, g.Groups)
+	}
+
+	wantAdlists := []GravityAdlist{
+		{ID: 1, Address: "https://example.com/hosts.txt", Enabled: true, Comment: "Main list", Groups: []int64{0, 1}},
+		{ID: 2, Address: "https://example.org/list.txt", Enabled: false, Groups: []int64{0}},
+	}
+	if !reflect.DeepEqual(g.Adlists, wantAdlists) {
+		t.Errorf("g.Adlists: expected %v, got %v", wantAdlists, g.Adlists)
+	}
+
+	if len(g.Domains) != 7 {
+		t.Fatalf("len(g.Domains): expected 7, got %d", len(g.Domains))
+	}
+	wantDomain := GravityDomain{ID: 1, Type: GravityExactAllow, Domain: "good.example.com", Enabled: true, Comment: "allowed", Groups: []int64{0}}
+	if !reflect.DeepEqual(g.Domains[0], wantDomain) {
+		t.Errorf("g.Domains[0]: expected %v, got %v", wantDomain, g.Domains[0])
+	}
+
+	wantEntries := []GravityEntry{
+		{Domain: "tracker.example.com", Adlist: 1},
+		{Domain: "off.example.org", Adlist: 2},
+	}
+	if !reflect.DeepEqual(g.Entries, wantEntries) {
+		t.Errorf("g.Entries: expected %v, got %v", wantEntries, g.Entries)
+	}
+}
+
+func TestGravityLoaderLoad(t *testing.T) {
+	g, err := ReadGravity(HelpGravityFixture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		filter filter.Filter
+		id     int64
+	}{
+		{filter.Filter{Exception: true, Domain: "good.example.com", Name: "domainlist"}, 1},
+		{filter.Filter{Domain: "ads.example.com", Name: "domainlist"}, 2},
+		{filter.Filter{Exception: true, Kind: filter.KindRegex, Pattern: `^allowed\.`, Name: "domainlist"}, 3},
+		{filter.Filter{Kind: filter.KindRegex, Pattern: `^ad[0-9]*\.`, Name: "domainlist"}, 4},
+		{filter.Filter{Domain: "kids.example.com", Name: "domainlist"}, 6},
+		{filter.Filter{Domain: "tracker.example.com", Name: "https://example.com/hosts.txt"}, 0},
+	}
+
+	l := NewGravityLoader(g)
+	for _, tc := range tt {
+		HelpLoaderTest(t, l, true, tc.filter, false)
+		if l.ID() != tc.id {
+			t.Errorf("l.ID(): expected %d, got %d", tc.id, l.ID())
+		}
+	}
+	HelpLoaderTest(t, l, false, filter.Filter{}, false)
+}
+
+func TestGravityLoaderGroup(t *testing.T) {
+	g, err := ReadGravity(HelpGravityFixture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewGravityLoader(g)
+	l.SetGroup(1)
+	HelpLoaderTest(t, l, true, filter.Filter{Domain: "kids.example.com", Name: "domainlist"}, false)
+	HelpLoaderTest(t, l, true, filter.Filter{Domain: "tracker.example.com", Name: "https://example.com/hosts.txt"}, false)
+	HelpLoaderTest(t, l, false, filter.Filter{}, false)
+}
+
+func TestGravityLoaderIDNAError(t *testing.T) {
+	g := &Gravity{
+		Domains: []GravityDomain{
+			{ID: 3, Type: GravityExactDeny, Domain: "--.com", Enabled: true},
+		},
+	}
+
+	l := NewGravityLoader(g)
+	HelpLoaderTest(t, l, true, filter.Filter{Domain: "--.com", Name: "domainlist"}, true)
+	HelpResourceErrorTest(t, "l.Err()", l.Err(), "domainlist", 0)
+	var entryErr *GravityEntryError
+	if !errors.As(l.Err(), &entryErr) || entryErr.Table != "domainlist" || entryErr.ID != 3 {
+		t.Errorf("l.Err(): expected *GravityEntryError for domainlist entry 3, got %#v", l.Err())
+	}
+	HelpLoaderTest(t, l, false, filter.Filter{}, false)
+}
+
+func TestWriteGravityRoundTrip(t *testing.T) {
+	fs := []filter.Filter{
+		{Domain: "ads.example.com", Name: "https://example.com/hosts.txt"},
+		{Exception: true, Domain: "good.example.com"},
+		{Kind: filter.KindRegex, Pattern: `^ad[0-9]*\.`},
+		{Exception: true, Kind: filter.KindGlob, Pattern: "*.allowed.example"},
+		{Domain: "ads.example.com", Name: "https://example.com/hosts.txt"},
+		{Domain: "tracker.example.com", Name: "https://example.com/hosts.txt"},
+		{Domain: "local.example", Name: "/etc/admasq/hosts.txt"},
+		{Domain: "local.example"},
+	}
+
+	db, err := OpenGravity(filepath.Join(t.TempDir(), "gravity.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := WriteGravity(db, GravityFromFilters(fs)); err != nil {
+		t.Fatalf("WriteGravity: %v", err)
+	}
+
+	g, err := ReadGravity(db)
+	if err != nil {
+		t.Fatalf("ReadGravity: %v", err)
+	}
+
+	wantAdlists := []GravityAdlist{{ID: 1, Address: "https://example.com/hosts.txt", Enabled: true, Groups: []int64{0}}}
+	if !reflect.DeepEqual(g.Adlists, wantAdlists) {
+		t.Errorf("g.Adlists: expected %v, got %v", wantAdlists, g.Adlists)
+	}
+	if len(g.Domains) != 4 || g.Domains[3].Comment != "/etc/admasq/hosts.txt" {
+		t.Errorf("g.Domains: expected the local domain once, commented with its source, got %v", g.Domains)
+	}
+
+	l := NewGravityLoader(g)
+	HelpLoaderTest(t, l, true, filter.Filter{Exception: true, Domain: "good.example.com", Name: "domainlist"}, false)
+	HelpLoaderTest(t, l, true, filter.Filter{Kind: filter.KindRegex, Pattern: `^ad[0-9]*\.`, Name: "domainlist"}, false)
+	HelpLoaderTest(t, l, true, filter.Filter{Exception: true, Kind: filter.KindRegex, Pattern: `^.*\.allowed\.example$`, Name: "domainlist"}, false)
+	HelpLoaderTest(t, l, true, filter.Filter{Domain: "local.example", Name: "domainlist"}, false)
+	HelpLoaderTest(t, l, true, filter.Filter{Domain: "ads.example.com", Name: "https://example.com/hosts.txt"}, false)
+	HelpLoaderTest(t, l, true, filter.Filter{Domain: "tracker.example.com", Name: "https://example.com/hosts.txt"}, false)
+	HelpLoaderTest(t, l, false, filter.Filter{}, false)
+
+	tt := []struct {
+		query string
+		want  int
+	}{
+		{`SELECT COUNT(*) FROM vw_gravity WHERE group_id = 0`, 2},
+		{`SELECT COUNT(*) FROM vw_blacklist WHERE group_id = 0`, 1},
+		{`SELECT number FROM adlist WHERE address = 'https://example.com/hosts.txt'`, 2},
+	}
+	for _, tc := range tt {
+		var n int
+		if err := db.QueryRow(tc.query).Scan(&n); err != nil {
+			t.Fatalf("%s: %v", tc.query, err)
+		}
+		if n != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.query, tc.want, n)
+		}
+	}
+}
+
+func HelpLoaderTest(t *testing.T, l filter.Loader, wantOK bool, wantF filter.Filter, wantHasErr bool) {
+	t.Helper()
+
+	gotOK := l.Load()
+	if gotOK != wantOK {
+		t.Errorf("ok: expected %t, got %t", wantOK, gotOK)
+	}
+
+	gotF := l.Filter()
+	if gotF != wantF {
+		t.Errorf("l.Filter(): expected %#v, got %#v", wantF, gotF)
+	}
+
+	gotErr := l.Err()
+	gotHasErr := gotErr != nil
+	if gotHasErr != wantHasErr {
+		t.Errorf("l.Err() != nil: expected %t, got %t", wantHasErr, gotHasErr)
+	}
+}
+
+func HelpResourceErrorTest(t *testing.T, name string, gotErr error, wantName string, wantLine int) {
+	t.Helper()
+
+	gotResErr, ok := gotErr.(*filter.ResourceError)
+	if !ok {
+		t.Fatalf("%s.(type): expected *filter.ResourceError, got %T", name, gotErr)
+	}
+
+	if gotResErr.Name != wantName {
+		t.Errorf("%s.Name: expected %q, got %q", name, wantName, gotResErr.Name)
+	}
+
+	if gotResErr.Line != wantLine {
+		t.Errorf("%s.Line: expected %d, got %d", name, wantLine, gotResErr.Line)
+	}
+}
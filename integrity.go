@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+var (
+	ErrChecksumNotFound  = errors.New("no checksum for the file")
+	ErrSignatureMissing  = errors.New("signature required but not provided")
+	ErrSignatureFormat   = errors.New("malformed minisign signature")
+	ErrSignatureKey      = errors.New("signature made with an unknown key")
+	ErrSignatureMismatch = errors.New("signature verification failed")
+	ErrPublicKeyFormat   = errors.New("malformed minisign public key")
+)
+
+type Integrity struct {
+	SHA256       string
+	SHA256URL    string
+	PublicKeys   []*MinisignPublicKey
+	SignatureURL string
+	Signature    []byte
+}
+
+func (in Integrity) Verify(b []byte) error {
+	if in.SHA256 != "" {
+		err := VerifySHA256(b, in.SHA256)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(in.PublicKeys) > 0 {
+		if len(in.Signature) <= 0 {
+			return ErrSignatureMissing
+		}
+		return VerifyMinisign(b, in.Signature, in.PublicKeys...)
+	}
+	return nil
+}
+
+func VerifySHA256(b []byte, want string) error {
+	sum := sha256.Sum256(b)
+	got := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(got, want) {
+		return &ChecksumError{Want: strings.ToLower(want), Got: got}
+	}
+	return nil
+}
+
+func ParseSHA256File(b []byte, name string) (string, error) {
+	base := path.Base(name)
+	if i := strings.IndexAny(base, "?#"); i >= 0 {
+		base = base[:i]
+	}
+
+	var bare []string
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var sum, file string
+		if rest, ok := strings.CutPrefix(line, "SHA256 ("); ok {
+			file, sum, ok = strings.Cut(rest, ") = ")
+			if !ok {
+				continue
+			}
+		} else {
+			sum, file, _ = strings.Cut(line, " ")
+			file = strings.TrimPrefix(strings.TrimSpace(file), "*")
+		}
+		if !isSHA256Hex(sum) {
+			continue
+		}
+
+		if file == "" {
+			bare = append(bare, strings.ToLower(sum))
+		} else if path.Base(file) == base {
+			return strings.ToLower(sum), nil
+		}
+	}
+
+	if len(bare) == 1 {
+		return bare[0], nil
+	}
+	return "", ErrChecksumNotFound
+}
+
+func isSHA256Hex(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+type ChecksumError struct {
+	Want string
+	Got  string
+}
+
+func (e *ChecksumError) Error() string {
+	return "sha256 mismatch: expected " + e.Want + ", got " + e.Got
+}
+
+type MinisignPublicKey struct {
+	KeyID uint64
+	Key   ed25519.PublicKey
+}
+
+func ParseMinisignPublicKey(s string) (*MinisignPublicKey, error) {
+	line := ""
+	for _, l := range strings.Split(s, "\n") {
+		l = strings.TrimSpace(l)
+		if l != "" && !strings.HasPrefix(l, "untrusted comment:") {
+			line = l
+			break
+		}
+	}
+
+	b, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || len(b) != 2+8+ed25519.PublicKeySize || string(b[:2]) != "Ed" {
+		return nil, ErrPublicKeyFormat
+	}
+	return &MinisignPublicKey{
+		KeyID: binary.LittleEndian.Uint64(b[2:10]),
+		Key:   ed25519.PublicKey(b[10:]),
+	}, nil
+}
+
+func VerifyMinisign(b, sig []byte, keys ...*MinisignPublicKey) error {
+	lines := bytes.Split(bytes.TrimRight(sig, "\r\n"), []byte("\n"))
+	if len(lines) < 4 {
+		return ErrSignatureFormat
+	}
+	for i := range lines {
+		lines[i] = bytes.TrimRight(lines[i], "\r")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(string(lines[1]))
+	if err != nil || len(raw) != 2+8+ed25519.SignatureSize {
+		return ErrSignatureFormat
+	}
+	alg, keyID, s := string(raw[:2]), binary.LittleEndian.Uint64(raw[2:10]), raw[10:]
+
+	comment, ok := bytes.CutPrefix(lines[2], []byte("trusted comment: "))
+	if !ok {
+		return ErrSignatureFormat
+	}
+	global, err := base64.StdEncoding.DecodeString(string(lines[3]))
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return ErrSignatureFormat
+	}
+
+	var key *MinisignPublicKey
+	for _, k := range keys {
+		if k.KeyID == keyID {
+			key = k
+			break
+		}
+	}
+	if key == nil {
+		return &SignatureKeyError{KeyID: keyID}
+	}
+
+	msg := b
+	switch alg {
+	case "Ed":
+	case "ED":
+		sum := blake2b.Sum512(b)
+		msg = sum[:]
+	default:
+		return ErrSignatureFormat
+	}
+
+	if !ed25519.Verify(key.Key, msg, s) {
+		return ErrSignatureMismatch
+	}
+	if !ed25519.Verify(key.Key, append(bytes.Clone(s), comment...), global) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+type SignatureKeyError struct {
+	KeyID uint64
+}
+
+func (e *SignatureKeyError) Error() string {
+	id := strings.ToUpper(strconv.FormatUint(e.KeyID, 16))
+	return ErrSignatureKey.Error() + " " + strings.Repeat("0", 16-len(id)) + id
+}
+
+func (e *SignatureKeyError) Unwrap() error {
+	return ErrSignatureKey
+}
+
+type IntegrityError struct {
+	URL string
+	Err error
+}
+
+func (e *IntegrityError) Error() string {
+	return e.URL + ": " + e.Err.Error()
+}
+
+func (e *IntegrityError) Unwrap() error {
+	return e.Err
+}
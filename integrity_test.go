@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+type HelpMinisignKey struct {
+	id   uint64
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+}
+
+func NewHelpMinisignKey(t *testing.T, id uint64) *HelpMinisignKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return &HelpMinisignKey{id: id, pub: pub, priv: priv}
+}
+
+func (k *HelpMinisignKey) PublicKey() string {
+	b := []byte("Ed")
+	b = binary.LittleEndian.AppendUint64(b, k.id)
+	b = append(b, k.pub...)
+	return "untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(b) + "\n"
+}
+
+func (k *HelpMinisignKey) Sign(alg string, msg []byte, comment string) []byte {
+	if alg == "ED" {
+		sum := blake2b.Sum512(msg)
+		msg = sum[:]
+	}
+	sig := ed25519.Sign(k.priv, msg)
+	global := ed25519.Sign(k.priv, append(append([]byte{}, sig...), comment...))
+
+	b := []byte(alg)
+	b = binary.LittleEndian.AppendUint64(b, k.id)
+	b = append(b, sig...)
+	return []byte("untrusted comment: signature\n" +
+		base64.StdEncoding.EncodeToString(b) + "\n" +
+		"trusted comment: " + comment + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n")
+}
+
+func HelpSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestVerifySHA256(t *testing.T) {
+	b := []byte("0.0.0.0 example.com\n")
+
+	err := VerifySHA256(b, HelpSHA256(b))
+	if err != nil {
+		t.Errorf("match: expected no error, got %v", err)
+	}
+
+	want := HelpSHA256([]byte("other"))
+	err = VerifySHA256(b, want)
+	var sumErr *ChecksumError
+	if !errors.As(err, &sumErr) || sumErr.Want != want || sumErr.Got != HelpSHA256(b) {
+		t.Errorf("mismatch: expected *ChecksumError, got %#v", err)
+	}
+}
+
+func TestParseSHA256File(t *testing.T) {
+	a := HelpSHA256([]byte("a"))
+	b := HelpSHA256([]byte("b"))
+
+	tt := []struct {
+		name    string
+		in      string
+		file    string
+		want    string
+		wantErr error
+	}{
+		{"Bare", a + "\n", "https://example.com/hosts.txt", a, nil},
+		{"Coreutils", a + "  other.txt\n" + b + "  hosts.txt\n", "https://example.com/lists/hosts.txt", b, nil},
+		{"Binary", a + " *hosts.txt\n", "hosts.txt", a, nil},
+		{"BSD", "SHA256 (other.txt) = " + a + "\nSHA256 (hosts.txt) = " + b + "\n", "hosts.txt", b, nil},
+		{"Query", a + "  other.txt\n" + b + "  hosts.txt\n", "https://example.com/hosts.txt?v=2", b, nil},
+		{"Missing", a + "  other.txt\n" + b + "  another.txt\n", "hosts.txt", "", ErrChecksumNotFound},
+		{"Invalid", "xyz  hosts.txt\n", "hosts.txt", "", ErrChecksumNotFound},
+		{"SingleOther", a + "  other.txt\n", "hosts.txt", "", ErrChecksumNotFound},
+		{"SingleOtherBSD", "SHA256 (other.txt) = " + a + "\n", "hosts.txt", "", ErrChecksumNotFound},
+		{"BareAmbiguous", a + "\n" + b + "\n", "hosts.txt", "", ErrChecksumNotFound},
+		{"BareAndOther", a + "\n" + b + "  other.txt\n", "hosts.txt", a, nil},
+	}
+
+	for _, tc := range tt {
+		got, err := ParseSHA256File([]byte(tc.in), tc.file)
+		if got != tc.want || err != tc.wantErr {
+			t.Errorf("%s: expected (%q, %v), got (%q, %v)", tc.name, tc.want, tc.wantErr, got, err)
+		}
+	}
+}
+
+func TestParseMinisignPublicKey(t *testing.T) {
+	k := NewHelpMinisignKey(t, 0x0123456789abcdef)
+
+	got, err := ParseMinisignPublicKey(k.PublicKey())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got.KeyID != k.id || !got.Key.Equal(k.pub) {
+		t.Errorf("expected key %X, got %X", k.id, got.KeyID)
+	}
+
+	for _, in := range []string{"", "untrusted comment: x\n", "RWQ=", base64.StdEncoding.EncodeToString(make([]byte, 42))} {
+		_, err := ParseMinisignPublicKey(in)
+		if err != ErrPublicKeyFormat {
+			t.Errorf("%q: expected ErrPublicKeyFormat, got %v", in, err)
+		}
+	}
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	k := NewHelpMinisignKey(t, 1)
+	other := NewHelpMinisignKey(t, 2)
+	pub, err := ParseMinisignPublicKey(k.PublicKey())
+	if err != nil {
+		t.Fatalf("parse key: %v", err)
+	}
+	msg := []byte("0.0.0.0 example.com\n")
+
+	for _, alg := range []string{"Ed", "ED"} {
+		sig := k.Sign(alg, msg, "timestamp:1700000000")
+		err := VerifyMinisign(msg, sig, pub)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", alg, err)
+		}
+
+		err = VerifyMinisign([]byte("0.0.0.0 evil.example\n"), sig, pub)
+		if err != ErrSignatureMismatch {
+			t.Errorf("%s tampered: expected ErrSignatureMismatch, got %v", alg, err)
+		}
+	}
+
+	sig := k.Sign("ED", msg, "timestamp:1700000000")
+	sig = bytes.Replace(sig, []byte("timestamp:1700000000"), []byte("timestamp:1800000000"), 1)
+	err = VerifyMinisign(msg, sig, pub)
+	if err != ErrSignatureMismatch {
+		t.Errorf("tampered comment: expected ErrSignatureMismatch, got %v", err)
+	}
+
+	err = VerifyMinisign(msg, other.Sign("ED", msg, ""), pub)
+	var keyErr *SignatureKeyError
+	if !errors.As(err, &keyErr) || keyErr.KeyID != 2 || !errors.Is(err, ErrSignatureKey) {
+		t.Errorf("unknown key: expected *SignatureKeyError, got %#v", err)
+	}
+
+	err = VerifyMinisign(msg, []byte("garbage"), pub)
+	if err != ErrSignatureFormat {
+		t.Errorf("garbage: expected ErrSignatureFormat, got %v", err)
+	}
+}
+
+func TestSignatureKeyErrorError(t *testing.T) {
+	tt := []struct {
+		in   uint64
+		want string
+	}{
+		{in: 0x0123456789abcdef, want: "signature made with an unknown key 0123456789ABCDEF"},
+		{in: 2, want: "signature made with an unknown key 0000000000000002"},
+	}
+
+	for _, tc := range tt {
+		got := (&SignatureKeyError{KeyID: tc.in}).Error()
+		if got != tc.want {
+			t.Errorf("%X: expected %q, got %q", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestIntegrityVerify(t *testing.T) {
+	k := NewHelpMinisignKey(t, 1)
+	pub, _ := ParseMinisignPublicKey(k.PublicKey())
+	msg := []byte("example.com\n")
+
+	tt := []struct {
+		name    string
+		in      Integrity
+		wantErr bool
+	}{
+		{"None", Integrity{}, false},
+		{"SHA256", Integrity{SHA256: HelpSHA256(msg)}, false},
+		{"SHA256Mismatch", Integrity{SHA256: HelpSHA256(nil)}, true},
+		{"Signature", Integrity{PublicKeys: []*MinisignPublicKey{pub}, Signature: k.Sign("ED", msg, "")}, false},
+		{"SignatureMissing", Integrity{PublicKeys: []*MinisignPublicKey{pub}}, true},
+	}
+
+	for _, tc := range tt {
+		err := tc.in.Verify(msg)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: expected error %t, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
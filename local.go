@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+type SourceFormat string
+
+const (
+	FormatHosts  SourceFormat = "hosts"
+	FormatSimple SourceFormat = "simple"
+	FormatRegex  SourceFormat = "regex"
+)
+
+var stdin io.Reader = os.Stdin
+
+func LocalSources(spec string, format SourceFormat) ([]filter.Source, error) {
+	if spec == "-" {
+		return []filter.Source{{Name: "-", Loader: NewFileLoader("-", format)}}, nil
+	}
+
+	// A file URL is not parsed as a URL: "?" and "#" are glob and name
+	// characters here, not a query and a fragment.
+	path := spec
+	if rest, ok := strings.CutPrefix(spec, "file://"); ok {
+		if p, ok := strings.CutPrefix(rest, "localhost/"); ok {
+			rest = "/" + p
+		}
+		path = filepath.FromSlash(rest)
+	}
+
+	var paths []string
+	if strings.ContainsAny(path, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) <= 0 {
+			return nil, &fs.PathError{Op: "glob", Path: path, Err: fs.ErrNotExist}
+		}
+		for _, m := range matches {
+			fi, err := os.Stat(m)
+			if err != nil {
+				return nil, err
+			}
+			if fi.Mode().IsRegular() {
+				paths = append(paths, m)
+			}
+		}
+	} else {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if fi.IsDir() {
+			ents, err := os.ReadDir(path)
+			if err != nil {
+				return nil, err
+			}
+			for _, ent := range ents {
+				if strings.HasPrefix(ent.Name(), ".") || !ent.Type().IsRegular() {
+					continue
+				}
+				paths = append(paths, filepath.Join(path, ent.Name()))
+			}
+		} else {
+			paths = append(paths, path)
+		}
+	}
+
+	srcs := make([]filter.Source, len(paths))
+	for i, p := range paths {
+		srcs[i] = filter.Source{Name: p, Loader: NewFileLoader(p, format)}
+	}
+	return srcs, nil
+}
+
+// CloseSources closes the loaders of srcs that hold an open file, such as
+// those returned by LocalSources, and returns the first error.
+func CloseSources(srcs []filter.Source) error {
+	var ret error
+	for _, src := range srcs {
+		c, ok := src.Loader.(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := c.Close(); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+// FileLoader reads filters from a local file or, for "-", from stdin.
+type FileLoader struct {
+	path   string
+	format SourceFormat
+
+	sniff SniffResult
+	l     filter.Loader
+	c     io.Closer
+	done  bool
+
+	f   filter.Filter
+	err error
+}
+
+func NewFileLoader(path string, format SourceFormat) *FileLoader {
+	return &FileLoader{path: path, format: format}
+}
+
+func (l *FileLoader) Format() SourceFormat {
+	return l.format
+}
+
+func (l *FileLoader) Sniff() SniffResult {
+	return l.sniff
+}
+
+func (l *FileLoader) Load() bool {
+	if l.done {
+		l.f = filter.Filter{}
+		l.err = nil
+		return false
+	}
+
+	if l.l == nil {
+		err := l.open()
+		if err != nil {
+			l.done = true
+			l.f = filter.Filter{}
+			l.err = &filter.ResourceError{Name: l.path, Err: err}
+			return false
+		}
+	}
+
+	if l.l.Load() {
+		l.f = l.l.Filter()
+		l.err = l.l.Err()
+		return true
+	}
+
+	l.done = true
+	l.f = filter.Filter{}
+	l.err = l.l.Err()
+	if _, ok := l.err.(*filter.ResourceError); !ok && l.err != nil {
+		l.err = &filter.ResourceError{Name: l.path, Err: l.err}
+	}
+	if err := l.Close(); err != nil && l.err == nil {
+		l.err = &filter.ResourceError{Name: l.path, Err: err}
+	}
+	return false
+}
+
+func (l *FileLoader) open() error {
+	var r io.Reader
+	if l.path == "-" {
+		r = stdin
+	} else {
+		f, err := os.Open(l.path)
+		if err != nil {
+			return err
+		}
+		l.c = f
+		r = f
+	}
+
+	br := bufio.NewReader(r)
+	var sniff *SniffResult
+	if l.format == "" || l.format == FormatAuto {
+		head, err := br.Peek(4096)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			l.Close()
+			return err
+		}
+		if err != io.EOF {
+			head = trimPartialLine(head)
+		}
+		l.sniff = DetectFileFormat(l.path, head)
+		l.format = l.sniff.Format
+		if l.format == "" && l.sniff.Lines <= 0 {
+			// Nothing but comments to load.
+			l.format = FormatSimple
+		}
+		sniff = &l.sniff
+	}
+	if !l.format.Loadable() {
+		l.Close()
+		return &FormatError{Format: l.format, Sniff: sniff}
+	}
+	l.l = newFormatLoader(l.format, br, l.path)
+	return nil
+}
+
+// Close closes the file. Load closes it once it returns false; a caller that
+// stops earlier must call Close, after which Load returns false.
+func (l *FileLoader) Close() error {
+	l.done = true
+	if l.c == nil {
+		return nil
+	}
+	err := l.c.Close()
+	l.c = nil
+	return err
+}
+
+func (l *FileLoader) Filter() filter.Filter { return l.f }
+func (l *FileLoader) Err() error            { return l.err }
+
+func newFormatLoader(format SourceFormat, r io.Reader, name string) filter.Loader {
+	switch format {
+	case FormatHosts:
+		l := filter.NewHostsLoader(r)
+		l.SetName(name)
+		return l
+	case FormatRegex:
+		l := filter.NewRegexLoader(r)
+		l.SetName(name)
+		return l
+	default:
+		l := filter.NewSimpleLoader(r)
+		l.SetName(name)
+		return l
+	}
+}
+
+func DetectFileFormat(path string, head []byte) SniffResult {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".hosts":
+		return SniffResult{Format: FormatHosts, Confidence: 1}
+	case ".regex":
+		return SniffResult{Format: FormatRegex, Confidence: 1}
+	case ".rpz":
+		return SniffResult{Format: FormatRPZ, Confidence: 1}
+	}
+	if strings.EqualFold(filepath.Base(path), "hosts") {
+		return SniffResult{Format: FormatHosts, Confidence: 1}
+	}
+	return SniffFormat(head)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+func HelpWriteFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		err = os.WriteFile(path, []byte(content), 0o644)
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	return dir
+}
+
+func HelpSourceNames(srcs []filter.Source) []string {
+	var names []string
+	for _, src := range srcs {
+		names = append(names, src.Name)
+	}
+	return names
+}
+
+func TestLocalSourcesGlob(t *testing.T) {
+	dir := HelpWriteFiles(t, map[string]string{
+		"a.txt":     "a.example.com\n",
+		"b.txt":     "0.0.0.0 b.example.com\n",
+		"c.regex":   "^c\\.\n",
+		"sub/d.txt": "d.example.com\n",
+	})
+
+	srcs, err := LocalSources("file://"+filepath.ToSlash(dir)+"/*.txt", "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	want := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}
+	if got := HelpSourceNames(srcs); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("names: expected %v, got %v", want, got)
+	}
+
+	_, err = LocalSources(filepath.Join(dir, "*.none"), "")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("no match: expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestLocalSourcesFileURL(t *testing.T) {
+	dir := HelpWriteFiles(t, map[string]string{
+		"ad1.txt":  "a.example.com\n",
+		"ad22.txt": "b.example.com\n",
+		"ad#1.txt": "c.example.com\n",
+	})
+	base := "file://" + filepath.ToSlash(dir)
+
+	tt := []struct {
+		spec string
+		want []string
+	}{
+		{base + "/ad?.txt", []string{filepath.Join(dir, "ad1.txt")}},
+		{base + "/ad??.txt", []string{filepath.Join(dir, "ad#1.txt"), filepath.Join(dir, "ad22.txt")}},
+		{base + "/ad#1.txt", []string{filepath.Join(dir, "ad#1.txt")}},
+		{"file://localhost" + filepath.ToSlash(dir) + "/ad1.txt", []string{filepath.Join(dir, "ad1.txt")}},
+	}
+
+	for _, tc := range tt {
+		srcs, err := LocalSources(tc.spec, "")
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tc.spec, err)
+			continue
+		}
+		if got := HelpSourceNames(srcs); strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: expected %v, got %v", tc.spec, tc.want, got)
+		}
+	}
+}
+
+func TestLocalSourcesDirectory(t *testing.T) {
+	dir := HelpWriteFiles(t, map[string]string{
+		"a.txt":     "a.example.com\n",
+		"b.hosts":   "0.0.0.0 b.example.com\n",
+		"c.regex":   "^c\\.\n",
+		".hidden":   "hidden.example.com\n",
+		"sub/d.txt": "d.example.com\n",
+	})
+
+	srcs, err := LocalSources(dir, "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l := filter.NewMultiLoader(srcs...)
+	defer l.Close()
+	HelpLoaderTest(t, l, true, filter.Filter{Domain: "a.example.com", Name: filepath.Join(dir, "a.txt"), Line: 1}, false)
+	HelpLoaderTest(t, l, true, filter.Filter{Domain: "b.example.com", Name: filepath.Join(dir, "b.hosts"), Line: 1}, false)
+	HelpLoaderTest(t, l, true, filter.Filter{Kind: filter.KindRegex, Pattern: "^c\\.", Name: filepath.Join(dir, "c.regex"), Line: 1}, false)
+	HelpLoaderTest(t, l, false, filter.Filter{}, false)
+}
+
+func TestLocalSourcesFile(t *testing.T) {
+	dir := HelpWriteFiles(t, map[string]string{"list": "0.0.0.0 a.example.com\n192.168.0.1 b.example.com\n"})
+	path := filepath.Join(dir, "list")
+
+	srcs, err := LocalSources(path, "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(srcs) != 1 || srcs[0].Name != path {
+		t.Fatalf("srcs: expected [%s], got %v", path, HelpSourceNames(srcs))
+	}
+
+	l := srcs[0].Loader
+	HelpLoaderTest(t, l, true, filter.Filter{Domain: "a.example.com", Name: path, Line: 1}, false)
+	HelpLoaderTest(t, l, true, filter.Filter{}, true)
+	HelpResourceErrorTest(t, "l.Err()", l.Err(), path, 2)
+	HelpLoaderTest(t, l, false, filter.Filter{}, false)
+
+	_, err = LocalSources(filepath.Join(dir, "missing"), "")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing: expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestLocalSourcesStdin(t *testing.T) {
+	orig := stdin
+	defer func() { stdin = orig }()
+	stdin = strings.NewReader("0.0.0.0 a.example.com\n")
+
+	srcs, err := LocalSources("-", "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l := srcs[0].Loader
+	HelpLoaderTest(t, l, true, filter.Filter{Domain: "a.example.com", Name: "-", Line: 1}, false)
+	HelpLoaderTest(t, l, false, filter.Filter{}, false)
+}
+
+func TestFileLoaderOpenError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.txt")
+	l := NewFileLoader(path, FormatSimple)
+
+	HelpLoaderTest(t, l, false, filter.Filter{}, true)
+	HelpResourceErrorTest(t, "l.Err()", l.Err(), path, 0)
+	if !errors.Is(l.Err(), fs.ErrNotExist) {
+		t.Errorf("l.Err(): expected fs.ErrNotExist, got %v", l.Err())
+	}
+	HelpLoaderTest(t, l, false, filter.Filter{}, false)
+}
+
+func TestFileLoaderClose(t *testing.T) {
+	dir := HelpWriteFiles(t, map[string]string{"a.txt": "a.example.com\n"})
+	l := NewFileLoader(filepath.Join(dir, "a.txt"), "")
+
+	for l.Load() {
+	}
+	if l.c != nil {
+		t.Error("l.c: expected the file to be closed after the last Load")
+	}
+	if l.Format() != FormatSimple {
+		t.Errorf("l.Format(): expected %s, got %s", FormatSimple, l.Format())
+	}
+}
+
+func TestFileLoaderCloseEarly(t *testing.T) {
+	dir := HelpWriteFiles(t, map[string]string{"a.txt": "a.example.com\nb.example.com\n"})
+	srcs, err := LocalSources(filepath.Join(dir, "a.txt"), "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l := srcs[0].Loader.(*FileLoader)
+
+	if !l.Load() {
+		t.Fatalf("l.Load(): expected true, got false (err: %v)", l.Err())
+	}
+	err = CloseSources(srcs)
+	if err != nil {
+		t.Errorf("CloseSources: expected no error, got %v", err)
+	}
+	if l.c != nil {
+		t.Error("l.c: expected the file to be closed")
+	}
+	HelpLoaderTest(t, l, false, filter.Filter{}, false)
+}
+
+func TestDetectFileFormat(t *testing.T) {
+	tt := []struct {
+		path string
+		head string
+		want SourceFormat
+	}{
+		{"list.hosts", "example.com\n", FormatHosts},
+		{"/etc/hosts", "", FormatHosts},
+		{"list.regex", "example.com\n", FormatRegex},
+		{"list.txt", "# comment\n\n0.0.0.0 example.com\n", FormatHosts},
+		{"list.txt", "::1\tlocalhost\n", FormatHosts},
+		{"list.txt", "# comment\nexample.com\n", FormatSimple},
+		{"list.txt", "^ad[0-9]*\\.\n", FormatRegex},
+		{"list.txt", "(^|\\.)tracker\\.com$\n", FormatRegex},
+		{"list.txt", "", ""},
+	}
+
+	for _, tc := range tt {
+		got := DetectFileFormat(tc.path, []byte(tc.head)).Format
+		if got != tc.want {
+			t.Errorf("%s %q: expected %s, got %s", tc.path, tc.head, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+type SourceMetrics struct {
+	Name          string
+	Lines         int64
+	Bytes         int64
+	Filters       int64
+	Errors        map[filter.ErrorKind]int64
+	FetchDuration time.Duration
+	LastSuccess   time.Time
+}
+
+type OutputMetrics struct {
+	Name      string
+	Rules     int64
+	Collapsed int64
+}
+
+type Metrics struct {
+	mu      sync.Mutex
+	builds  int64
+	sources []SourceMetrics
+	outputs []OutputMetrics
+	errs    map[metricsErrorKey]int64
+}
+
+type metricsErrorKey struct {
+	source string
+	kind   filter.ErrorKind
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{errs: map[metricsErrorKey]int64{}}
+}
+
+func SourceMetricsFromReport(r *filter.Report) []SourceMetrics {
+	ms := make([]SourceMetrics, len(r.Sources))
+	idx := make(map[string]int, len(r.Sources))
+	for i, src := range r.Sources {
+		ms[i] = SourceMetrics{
+			Name:    src.Name,
+			Filters: int64(src.Filters),
+			Errors:  map[filter.ErrorKind]int64{},
+		}
+		idx[src.Name] = i
+	}
+	for _, e := range r.Errors {
+		ms[idx[e.Source]].Errors[e.Kind]++
+	}
+	return ms
+}
+
+func (m *Metrics) ObserveBuild(srcs []SourceMetrics, outs []OutputMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prev := make(map[string]time.Time, len(m.sources))
+	for _, src := range m.sources {
+		prev[src.Name] = src.LastSuccess
+	}
+
+	m.builds++
+	m.sources = slices.Clone(srcs)
+	for i := range m.sources {
+		src := &m.sources[i]
+		if src.LastSuccess.IsZero() {
+			src.LastSuccess = prev[src.Name]
+		}
+		for kind, n := range src.Errors {
+			m.errs[metricsErrorKey{source: src.Name, kind: kind}] += n
+		}
+	}
+	m.outputs = slices.Clone(outs)
+}
+
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b metricsBuffer
+	b.header("admasq_builds_total", "counter", "Number of completed builds.")
+	b.sample("admasq_builds_total", nil, strconv.FormatInt(m.builds, 10))
+
+	b.header("admasq_source_lines", "gauge", "Lines read from the source in the last build.")
+	for _, src := range m.sources {
+		b.sample("admasq_source_lines", []string{"source", src.Name}, strconv.FormatInt(src.Lines, 10))
+	}
+	b.header("admasq_source_bytes", "gauge", "Bytes read from the source in the last build.")
+	for _, src := range m.sources {
+		b.sample("admasq_source_bytes", []string{"source", src.Name}, strconv.FormatInt(src.Bytes, 10))
+	}
+	b.header("admasq_source_filters", "gauge", "Filters emitted by the source in the last build.")
+	for _, src := range m.sources {
+		b.sample("admasq_source_filters", []string{"source", src.Name}, strconv.FormatInt(src.Filters, 10))
+	}
+	b.header("admasq_source_errors", "gauge", "Errors reported by the source in the last build, by kind.")
+	for _, src := range m.sources {
+		for _, kind := range slices.Sorted(maps.Keys(src.Errors)) {
+			b.sample("admasq_source_errors", []string{"source", src.Name, "kind", string(kind)}, strconv.FormatInt(src.Errors[kind], 10))
+		}
+	}
+	b.header("admasq_source_errors_total", "counter", "Errors reported by the source across all builds, by kind.")
+	keys := slices.SortedFunc(maps.Keys(m.errs), func(a, b metricsErrorKey) int {
+		if c := strings.Compare(a.source, b.source); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a.kind), string(b.kind))
+	})
+	for _, key := range keys {
+		b.sample("admasq_source_errors_total", []string{"source", key.source, "kind", string(key.kind)}, strconv.FormatInt(m.errs[key], 10))
+	}
+	b.header("admasq_source_fetch_duration_seconds", "gauge", "Time spent fetching the source in the last build.")
+	for _, src := range m.sources {
+		b.sample("admasq_source_fetch_duration_seconds", []string{"source", src.Name}, strconv.FormatFloat(src.FetchDuration.Seconds(), 'g', -1, 64))
+	}
+	b.header("admasq_source_last_success_timestamp_seconds", "gauge", "Unix time of the last build in which the source loaded without a terminal error.")
+	for _, src := range m.sources {
+		if !src.LastSuccess.IsZero() {
+			b.sample("admasq_source_last_success_timestamp_seconds", []string{"source", src.Name}, strconv.FormatInt(src.LastSuccess.Unix(), 10))
+		}
+	}
+
+	b.header("admasq_output_rules", "gauge", "Rules written to the output in the last build.")
+	for _, out := range m.outputs {
+		b.sample("admasq_output_rules", []string{"output", out.Name}, strconv.FormatInt(out.Rules, 10))
+	}
+	b.header("admasq_output_collapsed_rules", "gauge", "Rules dropped from the output in the last build because a parent domain covers them.")
+	for _, out := range m.outputs {
+		b.sample("admasq_output_collapsed_rules", []string{"output", out.Name}, strconv.FormatInt(out.Collapsed, 10))
+	}
+
+	return b.WriteTo(w)
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+func (m *Metrics) WriteTextfile(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = m.WriteTo(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Chmod(0o644)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+type metricsBuffer struct {
+	bytes.Buffer
+}
+
+func (b *metricsBuffer) header(name, typ, help string) {
+	b.WriteString("# HELP " + name + " " + help + "\n")
+	b.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+func (b *metricsBuffer) sample(name string, labels []string, value string) {
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(labels[i] + "=\"" + metricsLabelReplacer.Replace(labels[i+1]) + "\"")
+		}
+		b.WriteByte('}')
+	}
+	b.WriteString(" " + value + "\n")
+}
+
+var metricsLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type CountingReader struct {
+	r     io.Reader
+	bytes int64
+	lines int64
+	last  byte
+}
+
+func NewCountingReader(r io.Reader) *CountingReader {
+	return &CountingReader{r: r, last: '\n'}
+}
+
+func (r *CountingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.bytes += int64(n)
+		r.lines += int64(bytes.Count(p[:n], []byte{'\n'}))
+		r.last = p[n-1]
+	}
+	return n, err
+}
+
+func (r *CountingReader) Bytes() int64 {
+	return r.bytes
+}
+
+func (r *CountingReader) Lines() int64 {
+	if r.last != '\n' {
+		return r.lines + 1
+	}
+	return r.lines
+}
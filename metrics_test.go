@@ -0,0 +1,176 @@
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+func HelpMetrics() *Metrics {
+	m := NewMetrics()
+	m.ObserveBuild([]SourceMetrics{
+		{
+			Name:          "hosts.txt",
+			Lines:         3,
+			Bytes:         64,
+			Filters:       2,
+			Errors:        map[filter.ErrorKind]int64{filter.ErrorKindHostsIP: 1},
+			FetchDuration: 1500 * time.Millisecond,
+			LastSuccess:   time.Unix(1700000000, 0),
+		},
+		{
+			Name:  `we"ird\name`,
+			Lines: 0,
+		},
+	}, []OutputMetrics{
+		{Name: "dnsmasq", Rules: 2, Collapsed: 1},
+	})
+	return m
+}
+
+func TestMetricsWriteTo(t *testing.T) {
+	var b strings.Builder
+	_, err := HelpMetrics().WriteTo(&b)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	got := b.String()
+
+	want := []string{
+		"# TYPE admasq_builds_total counter\nadmasq_builds_total 1\n",
+		"admasq_source_lines{source=\"hosts.txt\"} 3\n",
+		"admasq_source_bytes{source=\"hosts.txt\"} 64\n",
+		"admasq_source_filters{source=\"hosts.txt\"} 2\n",
+		"admasq_source_errors{source=\"hosts.txt\",kind=\"HostsIPError\"} 1\n",
+		"admasq_source_errors_total{source=\"hosts.txt\",kind=\"HostsIPError\"} 1\n",
+		"admasq_source_fetch_duration_seconds{source=\"hosts.txt\"} 1.5\n",
+		"admasq_source_last_success_timestamp_seconds{source=\"hosts.txt\"} 1700000000\n",
+		"admasq_source_lines{source=\"we\\\"ird\\\\name\"} 0\n",
+		"admasq_output_rules{output=\"dnsmasq\"} 2\n",
+		"admasq_output_collapsed_rules{output=\"dnsmasq\"} 1\n",
+	}
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("output does not contain %q:\n%s", w, got)
+		}
+	}
+	if strings.Contains(got, "admasq_source_last_success_timestamp_seconds{source=\"we") {
+		t.Errorf("output contains a last success timestamp for a source that never succeeded:\n%s", got)
+	}
+}
+
+func TestMetricsObserveBuild(t *testing.T) {
+	m := HelpMetrics()
+	m.ObserveBuild([]SourceMetrics{
+		{
+			Name:   "hosts.txt",
+			Errors: map[filter.ErrorKind]int64{filter.ErrorKindHostsIP: 2},
+		},
+	}, nil)
+
+	var b strings.Builder
+	m.WriteTo(&b)
+	got := b.String()
+
+	want := []string{
+		"admasq_builds_total 2\n",
+		"admasq_source_errors{source=\"hosts.txt\",kind=\"HostsIPError\"} 2\n",
+		"admasq_source_errors_total{source=\"hosts.txt\",kind=\"HostsIPError\"} 3\n",
+		"admasq_source_last_success_timestamp_seconds{source=\"hosts.txt\"} 1700000000\n",
+		"admasq_source_filters{source=\"hosts.txt\"} 0\n",
+	}
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("output does not contain %q:\n%s", w, got)
+		}
+	}
+	if strings.Contains(got, "admasq_output_rules{") {
+		t.Errorf("output contains stale output metrics:\n%s", got)
+	}
+}
+
+func TestMetricsServeHTTP(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HelpMetrics().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	gotType := rec.Header().Get("Content-Type")
+	if !strings.HasPrefix(gotType, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type: expected text exposition format, got %q", gotType)
+	}
+	if !strings.Contains(rec.Body.String(), "admasq_builds_total 1\n") {
+		t.Errorf("body: expected metrics, got %q", rec.Body.String())
+	}
+}
+
+func TestMetricsWriteTextfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "admasq.prom")
+
+	err := HelpMetrics().WriteTextfile(path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !strings.Contains(string(b), "admasq_builds_total 1\n") {
+		t.Errorf("textfile: expected metrics, got %q", b)
+	}
+
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("readdir: %v", err)
+	}
+	if len(ents) != 1 {
+		t.Errorf("dir: expected only the textfile, got %d entries", len(ents))
+	}
+}
+
+func TestSourceMetricsFromReport(t *testing.T) {
+	r := filter.NewReport()
+	l := filter.NewHostsLoader(strings.NewReader("0.0.0.0 a.example.com\n192.168.0.1 b.example.com\n127.0.0.1\n"))
+	l.SetName("hosts.txt")
+	for l.Load() {
+		r.Add(l.Filter(), l.Err())
+	}
+
+	ms := SourceMetricsFromReport(r)
+	if len(ms) != 1 {
+		t.Fatalf("len: expected 1, got %d", len(ms))
+	}
+	if ms[0].Name != "hosts.txt" || ms[0].Filters != 1 {
+		t.Errorf("ms[0]: expected hosts.txt with 1 filter, got %s with %d", ms[0].Name, ms[0].Filters)
+	}
+	if ms[0].Errors[filter.ErrorKindHostsIP] != 1 || ms[0].Errors[filter.ErrorKindMissingHostname] != 1 {
+		t.Errorf("ms[0].Errors: got %v", ms[0].Errors)
+	}
+}
+
+func TestCountingReader(t *testing.T) {
+	tt := []struct {
+		in        string
+		wantBytes int64
+		wantLines int64
+	}{
+		{"", 0, 0},
+		{"a\n", 2, 1},
+		{"a\nb", 3, 2},
+		{"a\nb\n\n", 5, 3},
+	}
+
+	for _, tc := range tt {
+		r := NewCountingReader(strings.NewReader(tc.in))
+		io.Copy(io.Discard, r)
+		if r.Bytes() != tc.wantBytes || r.Lines() != tc.wantLines {
+			t.Errorf("%q: expected %d bytes and %d lines, got %d and %d", tc.in, tc.wantBytes, tc.wantLines, r.Bytes(), r.Lines())
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatAuto    SourceFormat = "auto"
+	FormatAdblock SourceFormat = "adblock"
+	FormatDnsmasq SourceFormat = "dnsmasq"
+	FormatRPZ     SourceFormat = "rpz"
+)
+
+var sniffFormats = []SourceFormat{FormatHosts, FormatSimple, FormatRegex, FormatAdblock, FormatDnsmasq, FormatRPZ}
+
+func ParseSourceFormat(s string) (SourceFormat, error) {
+	if s == "" || SourceFormat(s) == FormatAuto {
+		return FormatAuto, nil
+	}
+	for _, f := range sniffFormats {
+		if SourceFormat(s) == f {
+			return f, nil
+		}
+	}
+	return "", &FormatError{Format: SourceFormat(s)}
+}
+
+func (f SourceFormat) Loadable() bool {
+	switch f {
+	case FormatHosts, FormatSimple, FormatRegex:
+		return true
+	default:
+		return false
+	}
+}
+
+type SniffResult struct {
+	Format     SourceFormat
+	Confidence float64
+	Lines      int
+}
+
+func (r SniffResult) String() string {
+	format := string(r.Format)
+	if format == "" {
+		format = "undetermined"
+	}
+	if r.Lines <= 0 {
+		return format
+	}
+	return format + " (" + strconv.Itoa(int(r.Confidence*100+0.5)) + "% of " + strconv.Itoa(r.Lines) + " lines)"
+}
+
+const sniffLines = 50
+
+// SniffFormat votes on the format of head line by line. The result has no
+// Format when no line is recognised or when two formats tie; Confidence is
+// then the share of lines behind the leading formats.
+func SniffFormat(head []byte) SniffResult {
+	votes := map[SourceFormat]int{}
+	n := 0
+	for _, line := range bytes.Split(head, []byte("\n")) {
+		if i := bytes.Index(line, []byte(" #")); i >= 0 {
+			line = line[:i]
+		}
+		if i := bytes.Index(line, []byte("\t#")); i >= 0 {
+			line = line[:i]
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) <= 0 || line[0] == '#' {
+			continue
+		}
+		if n >= sniffLines {
+			break
+		}
+
+		n++
+		if f := sniffLine(string(line)); f != "" {
+			votes[f]++
+		}
+	}
+
+	if n <= 0 {
+		return SniffResult{}
+	}
+
+	var best SourceFormat
+	tie := false
+	for _, f := range sniffFormats {
+		switch {
+		case votes[f] > votes[best]:
+			best = f
+			tie = false
+		case votes[f] > 0 && votes[f] == votes[best]:
+			tie = true
+		}
+	}
+	r := SniffResult{
+		Format:     best,
+		Confidence: float64(votes[best]) / float64(n),
+		Lines:      n,
+	}
+	if tie {
+		r.Format = ""
+	}
+	return r
+}
+
+// trimPartialLine drops the last line of head if it does not end in a
+// newline, so that a line cut off by the read limit cannot vote.
+func trimPartialLine(head []byte) []byte {
+	return head[:bytes.LastIndexByte(head, '\n')+1]
+}
+
+func sniffLine(s string) SourceFormat {
+	switch {
+	case strings.HasPrefix(s, "!") || strings.HasPrefix(s, "[Adblock"):
+		return FormatAdblock
+	case strings.HasPrefix(s, "||") || strings.HasPrefix(s, "@@") || strings.HasSuffix(s, "^") || strings.Contains(s, "^$"):
+		return FormatAdblock
+	case strings.HasPrefix(s, "address=/") || strings.HasPrefix(s, "server=/") || strings.HasPrefix(s, "local=/"):
+		return FormatDnsmasq
+	case strings.HasPrefix(s, ";") || strings.HasPrefix(s, "$TTL") || strings.HasPrefix(s, "$ORIGIN"):
+		return FormatRPZ
+	}
+
+	fields := strings.Fields(s)
+	if _, err := netip.ParseAddr(fields[0]); err == nil {
+		return FormatHosts
+	}
+	for _, field := range fields[1:] {
+		switch strings.ToUpper(field) {
+		case "CNAME", "SOA", "NS", "A", "AAAA":
+			return FormatRPZ
+		}
+	}
+
+	if s[0] == '^' || s[len(s)-1] == '$' || strings.ContainsAny(s, `\()[]{}|+`) {
+		return FormatRegex
+	}
+	if len(fields) == 1 {
+		return FormatSimple
+	}
+	return ""
+}
+
+// FormatError reports a source format that cannot be loaded. Sniff is set
+// when the format was detected rather than given.
+type FormatError struct {
+	Format SourceFormat
+	Sniff  *SniffResult
+}
+
+func (e *FormatError) Error() string {
+	if e.Sniff == nil {
+		return "unsupported source format " + strconv.Quote(string(e.Format))
+	}
+	if e.Format == "" {
+		return "cannot detect source format: " + e.Sniff.String()
+	}
+	return "unsupported source format " + strconv.Quote(string(e.Format)) + ", detected as " + e.Sniff.String()
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gcrtnst/admasq/filter"
+)
+
+func TestSniffFormat(t *testing.T) {
+	tt := []struct {
+		name           string
+		in             string
+		wantFormat     SourceFormat
+		wantConfidence float64
+		wantLines      int
+	}{
+		{"Empty", "", "", 0, 0},
+		{"CommentsOnly", "# a\n# b\n", "", 0, 0},
+		{"Hosts", "# hosts\n127.0.0.1 localhost\n0.0.0.0 ads.example.com\n::1 ip6-localhost\n", FormatHosts, 1, 3},
+		{"Simple", "ads.example.com\ntracker.example.com # comment\n", FormatSimple, 1, 2},
+		{"Regex", "^ad[0-9]*\\.\n(^|\\.)tracker\\.com$\n", FormatRegex, 1, 2},
+		{"Adblock", "[Adblock Plus 2.0]\n! Title: list\n||ads.example.com^\n@@||good.example.com^$important\n", FormatAdblock, 1, 4},
+		{"Dnsmasq", "address=/ads.example.com/\nserver=/good.example.com/#\n", FormatDnsmasq, 1, 2},
+		{"RPZ", "$TTL 300\n; zone\n@ SOA localhost. root.localhost. 1 1h 15m 30d 2h\nads.example.com CNAME .\n*.ads.example.com CNAME .\n", FormatRPZ, 1, 5},
+		{"Mixed", "0.0.0.0 a.example.com\n0.0.0.0 b.example.com\n0.0.0.0 c.example.com\nd.example.com\n", FormatHosts, 0.75, 4},
+		{"Tie", "0.0.0.0 a.example.com\nb.example.com\n", "", 0.5, 2},
+		{"TieSimpleFirst", "a.example.com\n0.0.0.0 b.example.com\n", "", 0.5, 2},
+		{"TieAfterLead", "||a.example.com^\n||b.example.com^\naddress=/c.example.com/\naddress=/d.example.com/\ne.example.com\n", "", 0.4, 5},
+		{"Unrecognised", "a b c\nd e f\n", "", 0, 2},
+		{"CaretSuffix", "a.example.com^\nb.example.com^\n", FormatAdblock, 1, 2},
+		{"CaretOptions", "a.example.com^$third-party\nb.example.com^$important\n", FormatAdblock, 1, 2},
+	}
+
+	for _, tc := range tt {
+		got := SniffFormat([]byte(tc.in))
+		if got.Format != tc.wantFormat || got.Confidence != tc.wantConfidence || got.Lines != tc.wantLines {
+			t.Errorf("%s: expected %s %v %d, got %s %v %d", tc.name, tc.wantFormat, tc.wantConfidence, tc.wantLines, got.Format, got.Confidence, got.Lines)
+		}
+	}
+}
+
+func TestSniffFormatLimit(t *testing.T) {
+	var b []byte
+	for range sniffLines {
+		b = append(b, "0.0.0.0 ads.example.com\n"...)
+	}
+	for range sniffLines * 2 {
+		b = append(b, "ads.example.com\n"...)
+	}
+
+	got := SniffFormat(b)
+	if got.Format != FormatHosts || got.Lines != sniffLines {
+		t.Errorf("expected hosts over %d lines, got %s over %d", sniffLines, got.Format, got.Lines)
+	}
+}
+
+func TestSniffResultString(t *testing.T) {
+	tt := []struct {
+		in   SniffResult
+		want string
+	}{
+		{SniffResult{Format: FormatHosts, Confidence: 1}, "hosts"},
+		{SniffResult{Format: FormatHosts, Confidence: 0.75, Lines: 4}, "hosts (75% of 4 lines)"},
+		{SniffResult{Confidence: 0.5, Lines: 2}, "undetermined (50% of 2 lines)"},
+	}
+
+	for _, tc := range tt {
+		if got := tc.in.String(); got != tc.want {
+			t.Errorf("expected %q, got %q", tc.want, got)
+		}
+	}
+}
+
+func TestParseSourceFormat(t *testing.T) {
+	tt := []struct {
+		in      string
+		want    SourceFormat
+		wantErr bool
+	}{
+		{"", FormatAuto, false},
+		{"auto", FormatAuto, false},
+		{"hosts", FormatHosts, false},
+		{"rpz", FormatRPZ, false},
+		{"csv", "", true},
+	}
+
+	for _, tc := range tt {
+		got, err := ParseSourceFormat(tc.in)
+		if got != tc.want || (err != nil) != tc.wantErr {
+			t.Errorf("%q: expected (%s, error %t), got (%s, %v)", tc.in, tc.want, tc.wantErr, got, err)
+		}
+	}
+}
+
+func TestFileLoaderAuto(t *testing.T) {
+	dir := HelpWriteFiles(t, map[string]string{
+		"hosts.txt":   "# list\n0.0.0.0 a.example.com\n",
+		"adblock.txt": "[Adblock Plus 2.0]\n||a.example.com^\n",
+	})
+
+	path := filepath.Join(dir, "hosts.txt")
+	l := NewFileLoader(path, FormatAuto)
+	HelpLoaderTest(t, l, true, filter.Filter{Domain: "a.example.com", Name: path, Line: 2}, false)
+	if got := l.Sniff(); got.Format != FormatHosts || got.Confidence != 1 || got.Lines != 1 {
+		t.Errorf("l.Sniff(): expected hosts with full confidence, got %v", got)
+	}
+
+	path = filepath.Join(dir, "adblock.txt")
+	l = NewFileLoader(path, FormatAuto)
+	HelpLoaderTest(t, l, false, filter.Filter{}, true)
+	HelpResourceErrorTest(t, "l.Err()", l.Err(), path, 0)
+	var formatErr *FormatError
+	if !errors.As(l.Err(), &formatErr) || formatErr.Format != FormatAdblock || formatErr.Sniff == nil {
+		t.Fatalf("l.Err(): expected *FormatError for adblock, got %#v", l.Err())
+	}
+	want := `unsupported source format "adblock", detected as adblock (100% of 2 lines)`
+	if got := formatErr.Error(); got != want {
+		t.Errorf("formatErr.Error(): expected %q, got %q", want, got)
+	}
+	if l.c != nil {
+		t.Error("l.c: expected the file to be closed")
+	}
+}
+
+func TestFileLoaderAutoUndetermined(t *testing.T) {
+	dir := HelpWriteFiles(t, map[string]string{
+		"tie.txt":   "0.0.0.0 a.example.com\nb.example.com\n",
+		"empty.txt": "# nothing yet\n",
+	})
+
+	path := filepath.Join(dir, "tie.txt")
+	l := NewFileLoader(path, FormatAuto)
+	HelpLoaderTest(t, l, false, filter.Filter{}, true)
+	var formatErr *FormatError
+	if !errors.As(l.Err(), &formatErr) || formatErr.Format != "" {
+		t.Fatalf("l.Err(): expected *FormatError without a format, got %#v", l.Err())
+	}
+	want := "cannot detect source format: undetermined (50% of 2 lines)"
+	if got := formatErr.Error(); got != want {
+		t.Errorf("formatErr.Error(): expected %q, got %q", want, got)
+	}
+
+	path = filepath.Join(dir, "empty.txt")
+	l = NewFileLoader(path, FormatAuto)
+	HelpLoaderTest(t, l, false, filter.Filter{}, false)
+}
+
+func TestFileLoaderAutoPartialLine(t *testing.T) {
+	// The 4096-byte window ends three bytes into the fifth hosts line,
+	// which would otherwise vote for simple as "0.0".
+	b := []byte("#" + strings.Repeat("x", 4003) + "\n")
+	for range 8 {
+		b = append(b, "0.0.0.0 a.example.com\n"...)
+	}
+	dir := HelpWriteFiles(t, map[string]string{"hosts.txt": string(b)})
+
+	l := NewFileLoader(filepath.Join(dir, "hosts.txt"), FormatAuto)
+	defer l.Close()
+	if !l.Load() {
+		t.Fatalf("l.Load(): expected true, got false (err: %v)", l.Err())
+	}
+	if got := l.Sniff(); got.Format != FormatHosts || got.Confidence != 1 || got.Lines != 4 {
+		t.Errorf("l.Sniff(): expected hosts (100%% of 4 lines), got %v", got)
+	}
+}
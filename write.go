@@ -0,0 +1,7 @@
+package main
+
+import (
+	"errors"
+)
+
+var ErrPatternUnsupported = errors.New("regex and glob filters are not supported by this format")